@@ -1,6 +1,8 @@
 package authmanager
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/crearosoft/corelib/loggermanager"
@@ -11,6 +13,25 @@ import (
 // GlobalJWTKey - signature key
 var GlobalJWTKey string
 
+func init() {
+	loggermanager.RegisterErrorMapper(func(err error) *loggermanager.CoreError {
+		var verr *jwt.ValidationError
+		if !errors.As(err, &verr) {
+			return nil
+		}
+		return tokenError(err)
+	})
+}
+
+// tokenError gives a jwt parse or validation error its catalog code
+func tokenError(err error) *loggermanager.CoreError {
+	var verr *jwt.ValidationError
+	if errors.As(err, &verr) && verr.Errors&jwt.ValidationErrorExpired != 0 {
+		return loggermanager.Wrapf(err, loggermanager.CodeTokenExpired, "token expired")
+	}
+	return loggermanager.Wrapf(err, loggermanager.CodeInvalidToken, "invalid token")
+}
+
 var keyFunc = func(key string) jwt.Keyfunc {
 	return func(*jwt.Token) (interface{}, error) {
 		return []byte(key), nil
@@ -46,12 +67,16 @@ func GenerateToken(loginID string, ExpiresAt int64) (string, error) {
 	// loggermanager.LogInfo(claims)
 }
 
-func decode(token *jwt.Token, err error) (jwt.MapClaims, error) {
+func decode(ctx context.Context, token *jwt.Token, err error) (jwt.MapClaims, error) {
+	if err != nil || token == nil || !token.Valid {
+		loggermanager.ContextLogger(ctx, "authmanager").Warn("invalid jwt token", loggermanager.Err(err))
+		return nil, tokenError(err)
+	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		// loggermanager.LogError("Error while parsing claims to MapClaims")
-		return nil, loggermanager.Wrap("Error while parsing claims")
+		loggermanager.ContextLogger(ctx, "authmanager").Error("Error while parsing claims to MapClaims")
+		return nil, loggermanager.New(loggermanager.CodeInvalidClaims, "Error while parsing claims")
 		// return nil, ok
 	}
 
@@ -59,6 +84,15 @@ func decode(token *jwt.Token, err error) (jwt.MapClaims, error) {
 }
 
 // DecodeJWTToken - decode token
+//
+// Tokens with a wrong signature, malformed or expired are rejected with INVALID_TOKEN or
+// TOKEN_EXPIRED. Earlier releases returned their claims, see CHANGELOG.md.
 func DecodeJWTToken(token string) (jwt.MapClaims, error) {
-	return decode(jwt.Parse(token, keyFunc(GlobalJWTKey)))
+	return DecodeJWTTokenWithContext(context.Background(), token)
+}
+
+// DecodeJWTTokenWithContext - decode token, logging with the correlation values of ctx
+func DecodeJWTTokenWithContext(ctx context.Context, token string) (jwt.MapClaims, error) {
+	t, err := jwt.Parse(token, keyFunc(GlobalJWTKey))
+	return decode(ctx, t, err)
 }
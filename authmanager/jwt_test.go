@@ -0,0 +1,40 @@
+package authmanager
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/crearosoft/corelib/loggermanager"
+)
+
+func TestDecodeJWTToken(t *testing.T) {
+	prev := GlobalJWTKey
+	defer func() { GlobalJWTKey = prev }()
+	GlobalJWTKey = "secret"
+
+	valid, _ := GenerateToken("ana", time.Now().Add(time.Hour).Unix())
+	expired, _ := GenerateToken("ana", time.Now().Add(-time.Hour).Unix())
+	GlobalJWTKey = "other"
+	forged, _ := GenerateToken("ana", time.Now().Add(time.Hour).Unix())
+	GlobalJWTKey = "secret"
+
+	claims, err := DecodeJWTToken(valid)
+	if err != nil || claims["username"] != "ana" {
+		t.Errorf("DecodeJWTToken(valid) = %v, %v", claims, err)
+	}
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"expired", expired, loggermanager.ErrTokenExpired},
+		{"wrong signature", forged, loggermanager.ErrInvalidToken},
+		{"malformed", "not.a.token", loggermanager.ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		if claims, err := DecodeJWTToken(tt.token); claims != nil || !errors.Is(err, tt.want) {
+			t.Errorf("%s: DecodeJWTToken() = %v, %v, want %v", tt.name, claims, err, tt.want)
+		}
+	}
+}
@@ -1,13 +1,18 @@
 package cachemanager
 
 import (
+	"strconv"
 	"time"
+
+	"github.com/crearosoft/corelib/loggermanager"
 )
 
 const (
 	// TypeCache indicates fast cache as cache storage
 	TypeCache = iota + 1
 	TypeRedisCache
+	// TypeLayeredCache indicates fast cache in front of redis, see LayeredCache
+	TypeLayeredCache
 )
 
 // Cache provides access to underlying cache, make sure all caches implement these methods.
@@ -16,11 +21,11 @@ type Cache interface {
 	Set(key string, val interface{})
 	SetWithExpiration(key string, val interface{}, exp time.Duration)
 	SetNoExpiration(key string, val interface{})
-	SaveFile(fname string)
+	SaveFile(fname string) error
 	// Getters
 	Get(key string) (interface{}, bool)
 	GetAll() map[string]interface{}
-	LoadFile(fname string)
+	LoadFile(fname string) error
 
 	// Deletion operations
 	Delete(key string)
@@ -31,3 +36,84 @@ type Cache interface {
 
 	Type() int
 }
+
+var (
+	_ Cache = (*CacheHelper)(nil)
+	_ Cache = (*RedisCache)(nil)
+)
+
+// CacheOptions configures NewCache. Fields not used by the requested kind are ignored.
+type CacheOptions struct {
+	Expiration time.Duration // used by Set, 0 keeps items forever
+
+	// TypeCache, L1 of TypeLayeredCache
+	MaxEntries      int
+	MaxBytes        int64
+	Policy          EvictionPolicy
+	CleanupInterval time.Duration
+
+	// TypeRedisCache, L2 of TypeLayeredCache
+	Addr          string
+	Password      string
+	DB            int
+	Prefix        string
+	Codec         Codec
+	Compression   Compression
+	CompressAbove int
+
+	// TypeLayeredCache
+	L1Expiration time.Duration // default 10s
+}
+
+// NewCache returns a cache of kind, TypeCache, TypeRedisCache or TypeLayeredCache, so callers depend only on Cache
+func NewCache(kind int, opts CacheOptions) (Cache, error) {
+	switch kind {
+	case TypeCache:
+		return newMemoryCache(opts), nil
+	case TypeRedisCache:
+		rc, err := newRedisCache(opts)
+		if err != nil {
+			// a nil *RedisCache would make a non nil Cache
+			return nil, err
+		}
+		return rc, nil
+	case TypeLayeredCache:
+		rc, err := newRedisCache(opts)
+		if err != nil {
+			return nil, err
+		}
+		var lopts []layeredOption
+		if opts.L1Expiration > 0 {
+			lopts = append(lopts, LayeredWithL1Expiration(opts.L1Expiration))
+		}
+		lc, err := NewLayeredCache(newMemoryCache(opts), rc, lopts...)
+		if err != nil {
+			rc.cli.Close()
+			return nil, err
+		}
+		return lc, nil
+	}
+	return nil, loggermanager.New(loggermanager.CodeInvalidArgument, "unknown cache type "+strconv.Itoa(kind)).WithDetail("type", kind)
+}
+
+func newMemoryCache(opts CacheOptions) *CacheHelper {
+	return SetupCache(
+		WithMaxEntries(opts.MaxEntries),
+		WithMaxBytes(opts.MaxBytes),
+		WithEvictionPolicy(opts.Policy),
+		WithExpiration(opts.Expiration),
+		WithCleanupInterval(opts.CleanupInterval),
+	)
+}
+
+func newRedisCache(opts CacheOptions) (*RedisCache, error) {
+	return SetupRedisCache(
+		RedisWithAddr(opts.Addr),
+		RedisWithPassword(opts.Password),
+		RedisWithDB(opts.DB),
+		RedisWithPrefix(opts.Prefix),
+		RedisWithExpiration(opts.Expiration),
+		RedisWithCodec(opts.Codec),
+		RedisWithCompression(opts.Compression, opts.CompressAbove),
+	)
+}
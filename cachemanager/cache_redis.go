@@ -15,8 +15,8 @@ Official docs -
 
 import (
 	"encoding/json"
-	"errors"
 	"log"
+	"os"
 	"strings"
 	"time"
 	"context"
@@ -31,27 +31,38 @@ const (
 )
 var ctx = context.Background()
 
+var logger = loggermanager.Named("cachemanager")
+
 // RedisCache represents a Redis client with provided configuration. Do not change configuration at runtime.
 type RedisCache struct {
-	cli       *redis.Client  // represents redis client
-	opt       *redis.Options //
-	keyStr    string         // "<Prefix>:"
-	addPrefix bool           //
-	connected bool           // will be enabled if redis client connects to server
+	cli       *redis.Client        // represents redis client
+	opt       *redis.Options       //
+	keyStr    string               // "<Prefix>:"
+	addPrefix bool                 //
+	connected bool                 // will be enabled if redis client connects to server
+	reqCtx    context.Context      // set by WithContext, used for redis calls and log correlation
+	reqLog    loggermanager.Logger // logger of reqCtx
 
 	Addr       string        // redis server address, default "127.0.0.1:6379"
 	DB         int           // redis DB on provided server, default 0
 	Password   string        //
 	Expiration time.Duration // this duration will be used for Set() method
 	Prefix     string        // this will be used for storing keys for provided project
+
+	Codec         Codec       // encodes values other than strings and bytes, default JSONCodec
+	Compression   Compression // compresses values of at least CompressAbove bytes
+	CompressAbove int         // default 1024
 }
 
 type configRedis struct {
-	addr       string        // redis server address, default "127.0.0.1:6379"
-	db         int           // redis DB on provided server, default 0
-	password   string        //
-	expiration time.Duration // this duration will be used for Set() method
-	prefix     string        // this will be used for storing keys for provided project
+	addr          string        // redis server address, default "127.0.0.1:6379"
+	db            int           // redis DB on provided server, default 0
+	password      string        //
+	expiration    time.Duration // this duration will be used for Set() method
+	prefix        string        // this will be used for storing keys for provided project
+	codec         Codec         //
+	compression   Compression   //
+	compressAbove int           //
 }
 
 type redisOption func(*configRedis)
@@ -82,6 +93,22 @@ func RedisWithExpiration(exp time.Duration) redisOption {
 	}
 }
 
+// RedisWithCodec sets the codec of values other than strings and bytes, default JSONCodec.
+// Values keep the codec they were stored with, so changing it does not break existing keys.
+func RedisWithCodec(c Codec) redisOption {
+	return func(cfg *configRedis) {
+		cfg.codec = c
+	}
+}
+
+// RedisWithCompression compresses values of at least threshold bytes, 0 uses 1024
+func RedisWithCompression(c Compression, threshold int) redisOption {
+	return func(cfg *configRedis) {
+		cfg.compression = c
+		cfg.compressAbove = threshold
+	}
+}
+
 // Setup initializes redis cache for application. Must be called only once.
 func (rc *RedisCache) Setup(addr, password, prefix string, db int, exp time.Duration) {
 
@@ -105,7 +132,7 @@ func (rc *RedisCache) Setup(addr, password, prefix string, db int, exp time.Dura
 
 	if _, err := rc.cli.Ping(ctx).Result(); err != nil {
 		// exit if connection to redis server fails
-		loggermanager.LogError("connection to redis server failed: ", err)
+		logger.Error("connection to redis server failed", "addr", addr, loggermanager.Err(err))
 		log.Fatal("connection to redis server failed: ", err)
 	}
 
@@ -134,6 +161,9 @@ func SetupRedisCache(opts ...redisOption) (*RedisCache, error) {
 	rc.DB = cfg.db
 	rc.Expiration = cfg.expiration
 	rc.Prefix = cfg.prefix
+	rc.Codec = cfg.codec
+	rc.Compression = cfg.compression
+	rc.CompressAbove = cfg.compressAbove
 
 	rc.opt = &redis.Options{
 		Addr:     cfg.addr,
@@ -145,7 +175,7 @@ func SetupRedisCache(opts ...redisOption) (*RedisCache, error) {
 
 	if _, err := rc.cli.Ping(ctx).Result(); err != nil {
 
-		return nil, errors.New("connection to redis server failed: " + err.Error())
+		return nil, loggermanager.Wrapf(err, loggermanager.CodeRedisConnectionFailed, "connection to redis server failed").WithDetail("addr", cfg.addr)
 	}
 
 	rc.connected = true
@@ -158,56 +188,114 @@ func SetupRedisCache(opts ...redisOption) (*RedisCache, error) {
 	return rc, nil
 }
 
+// WithContext returns a shallow copy of rc which uses ctx for redis calls and logs with the correlation values of ctx.
+func (rc *RedisCache) WithContext(ctx context.Context) *RedisCache {
+	c := *rc
+	c.reqCtx = ctx
+	c.reqLog = loggermanager.ContextLogger(ctx, "cachemanager")
+	return &c
+}
+
+func (rc *RedisCache) context() context.Context {
+	if rc.reqCtx != nil {
+		return rc.reqCtx
+	}
+	return ctx
+}
+
+func (rc *RedisCache) log() loggermanager.Logger {
+	if rc.reqLog == nil {
+		return logger
+	}
+	return rc.reqLog
+}
+
 // Set marshalls provided value and stores against provided key. Errors will be logged to initialized logger.
 func (rc *RedisCache) Set(key string, val interface{}) {
-	ba, err := marshalWithTypeCheck(val)
+	ba, err := rc.encoder().encode(val)
 	if err != nil {
-		loggermanager.LogError("error setting key ", key, " error: ", err)
+		rc.log().Error("error setting key", "key", key, loggermanager.Err(err))
 		return
 	}
 
-	rc.cli.Set(ctx,rc.key(key), ba, rc.Expiration)
+	rc.cli.Set(rc.context(), rc.key(key), ba, rc.Expiration)
 }
 
 // SetWithExpiration marshalls provided value and stores against provided key for given duration. Errors will be logged to initialized logger.
 func (rc *RedisCache) SetWithExpiration(key string, val interface{}, exp time.Duration) {
-	ba, err := marshalWithTypeCheck(val)
+	ba, err := rc.encoder().encode(val)
 	if err != nil {
-		loggermanager.LogError("error setting key ", key, " error: ", err)
+		rc.log().Error("error setting key", "key", key, loggermanager.Err(err))
 		return
 	}
 
-	rc.cli.Set(ctx,rc.key(key), ba, exp)
+	rc.cli.Set(rc.context(), rc.key(key), ba, exp)
 }
 
 // SetNoExpiration marshalls provided value and stores against provided key.
 // Errors will be logged to initialized logger.
 func (rc *RedisCache) SetNoExpiration(key string, val interface{}) {
-	ba, err := marshalWithTypeCheck(val)
+	ba, err := rc.encoder().encode(val)
 	if err != nil {
-		loggermanager.LogError("error setting key ", key, " error: ", err)
+		rc.log().Error("error setting key", "key", key, loggermanager.Err(err))
 		return
 	}
 
-	rc.cli.Set(ctx,rc.key(key), ba, noExp)
+	rc.cli.Set(rc.context(), rc.key(key), ba, noExp)
 }
 
-// Get returns data against provided key. Returns false if not present.
+// Get returns data against provided key as a string, encoded by the codec it was stored with. Returns false if not present.
+// Use GetInto to decode values.
 func (rc *RedisCache) Get(key string) (interface{}, bool) {
 
 	// Get returns error if key is not present.
-	val, err := rc.cli.Get(ctx,rc.key(key)).Result()
+	val, err := rc.getRaw(key)
+	if err == redis.Nil {
+		rc.log().Debug("key not found in redis cache", "key", key)
+		return nil, false
+	}
+	if err != nil {
+		rc.log().Error("error getting key from redis cache", "key", key, loggermanager.Err(err))
+		return nil, false
+	}
+	_, payload, err := decodeValue(val)
 	if err != nil {
-		loggermanager.LogError("error getting key", key, "from redis cache with error:", err)
+		rc.log().Error("error decompressing value from redis cache", "key", key, loggermanager.Err(err))
 		return nil, false
 	}
 
-	return val, true
+	return string(payload), true
+}
+
+// GetInto decodes the value of key into v, a pointer, with the codec it was stored with.
+// Strings and bytes are assigned to a *string or *[]byte and decoded as JSON otherwise.
+// Returns false if key is not present.
+func (rc *RedisCache) GetInto(key string, v interface{}) (bool, error) {
+	val, err := rc.getRaw(key)
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, loggermanager.Wrapf(err, loggermanager.CodeCacheUnavailable, "error getting key from redis cache").WithDetail("key", key)
+	}
+	if err := unmarshalValue(val, v); err != nil {
+		return true, loggermanager.Wrapf(err, loggermanager.CodeCacheDecodeFailed, "error decoding cached value").WithDetail("key", key)
+	}
+	return true, nil
+}
+
+// getRaw returns the stored value of key with its header, redis.Nil if not present
+func (rc *RedisCache) getRaw(key string) ([]byte, error) {
+	return rc.cli.Get(rc.context(), rc.key(key)).Bytes()
+}
+
+func (rc *RedisCache) encoder() valueCodec {
+	return valueCodec{codec: rc.Codec, compression: rc.Compression, compressAbove: rc.CompressAbove}
 }
 
 // Delete -
 func (rc *RedisCache) Delete(key string) {
-	rc.cli.Del(ctx,rc.key(key)).Result()
+	rc.cli.Del(rc.context(), rc.key(key)).Result()
 }
 
 // GetItemsCount -
@@ -222,31 +310,33 @@ func (rc *RedisCache) GetItemsCount() int {
 }
 
 func (rc *RedisCache) flushDB() (string, error) {
-	return rc.cli.FlushDB(ctx).Result()
+	return rc.cli.FlushDB(rc.context()).Result()
 }
 
 // Purge deletes for current redis db
 func (rc *RedisCache) Purge() {
 	_, err := rc.flushDB()
 	if err != nil {
-		loggermanager.LogError("error purging redis cache for db ", rc.Addr, "/", rc.DB, " error: ", err)
+		rc.log().Error("error purging redis cache", "addr", rc.Addr, "db", rc.DB, loggermanager.Err(err))
 	}
 }
 
-func marshal(v interface{}) ([]byte, error) {
-	return json.Marshal(v)
-}
-
-// marshalWithTypeCheck checks type before marshsal. Save allocations and time significantly if the existing data is string or []byte
-func marshalWithTypeCheck(v interface{}) ([]byte, error) {
-	switch d := v.(type) {
-	default:
-		return json.Marshal(v)
-	case string:
-		return []byte(d), nil
-	case []byte:
-		return d, nil
+// decodeAny decodes a stored value into interface{} with the codec it was stored with.
+// Values which can not be decoded, such as strings or gob, are returned as strings.
+func decodeAny(data []byte) interface{} {
+	id, payload, err := decodeValue(data)
+	if err != nil {
+		return nil
+	}
+	codec, ok := codecByID(id)
+	if id == codecRaw {
+		codec, ok = JSONCodec{}, true
+	}
+	var val interface{}
+	if ok && codec.Unmarshal(payload, &val) == nil {
+		return val
 	}
+	return string(payload)
 }
 
 func contcat(s ...string) string {
@@ -286,16 +376,13 @@ func (rc *RedisCache) GetAll() map[string]interface{} {
 	result := make(map[string]interface{}, len(keys))
 
 	for i := range keys {
-		ba, err := rc.cli.Get(ctx,keys[i]).Bytes()
+		ba, err := rc.cli.Get(rc.context(), keys[i]).Bytes()
 		if err != nil {
-			loggermanager.LogError("error getting key", keys[i], "from redis cache with error:", err)
+			rc.log().Error("error getting key from redis cache", "key", keys[i], loggermanager.Err(err))
 			continue
 		}
 
-		var val interface{}
-		_ = json.Unmarshal(ba, &val)
-
-		result[rc.actualKey(keys[i])] = val
+		result[rc.actualKey(keys[i])] = decodeAny(ba)
 	}
 
 	return result
@@ -304,9 +391,91 @@ func (rc *RedisCache) GetAll() map[string]interface{} {
 // GetItemsCount -
 func (rc *RedisCache) keys() []string {
 	pattern := rc.Prefix + "*"
-	keys, err := rc.cli.Keys(ctx,pattern).Result()
+	keys, err := rc.cli.Keys(rc.context(), pattern).Result()
 	if err != nil {
-		loggermanager.LogError("error getting item count for ", pattern, " error: ", err)
+		rc.log().Error("error listing keys", "pattern", pattern, loggermanager.Err(err))
 	}
 	return keys
 }
+
+// redisFileItem is one key of a file written by RedisCache.SaveFile
+type redisFileItem struct {
+	Value      []byte `json:"value"`
+	Expiration int64  `json:"expiration,omitempty"` // unix nano, 0 never expires
+}
+
+// SaveFile writes the keys of rc with their values and expirations to fname as JSON, see LoadFile.
+// Keys are saved without the prefix so they can be loaded into a cache with another prefix.
+func (rc *RedisCache) SaveFile(fname string) error {
+	keys := rc.keys()
+	pipe := rc.cli.Pipeline()
+	vals := make([]*redis.StringCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i := range keys {
+		vals[i] = pipe.Get(rc.context(), keys[i])
+		ttls[i] = pipe.PTTL(rc.context(), keys[i])
+	}
+	if _, err := pipe.Exec(rc.context()); err != nil && err != redis.Nil {
+		return loggermanager.Wrapf(err, loggermanager.CodeCacheUnavailable, "Error while reading the keys from redis").WithDetail("addr", rc.Addr)
+	}
+
+	now := time.Now()
+	items := make(map[string]redisFileItem, len(keys))
+	for i := range keys {
+		val, err := vals[i].Bytes()
+		if err != nil {
+			// expired or deleted meanwhile
+			continue
+		}
+		item := redisFileItem{Value: val}
+		if ttl := ttls[i].Val(); ttl > 0 {
+			item.Expiration = now.Add(ttl).UnixNano()
+		}
+		items[rc.actualKey(keys[i])] = item
+	}
+	b, err := json.Marshal(items)
+	if err != nil {
+		return loggermanager.Wrapf(err, loggermanager.CodeCacheMarshalFailed, "Error while marshalling the data")
+	}
+
+	f, err := createFile(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err = f.Write(b); err != nil {
+		return loggermanager.Wrapf(err, loggermanager.CodeCacheFileWriteFailed, "Error while writing the data to file").WithDetail("file", fname)
+	}
+	return nil
+}
+
+// LoadFile stores the keys of a file written by SaveFile, keeping their remaining expiration.
+// Existing keys are overwritten, expired ones are skipped.
+func (rc *RedisCache) LoadFile(fname string) error {
+	f, err := os.Open(fname)
+	if err != nil {
+		return loggermanager.Wrapf(err, loggermanager.CodeCacheFileReadFailed, "Error while reading file").WithDetail("file", fname)
+	}
+	defer f.Close()
+	items := make(map[string]redisFileItem)
+	if err := json.NewDecoder(f).Decode(&items); err != nil {
+		rc.log().Error("Error while binding the data from file", "file", fname, loggermanager.Err(err))
+		return loggermanager.Wrapf(err, loggermanager.CodeCacheFileDecodeFailed, "Error while binding the data from file").WithDetail("file", fname)
+	}
+
+	now := time.Now()
+	pipe := rc.cli.Pipeline()
+	for key, item := range items {
+		exp := noExp
+		if item.Expiration > 0 {
+			if exp = time.Unix(0, item.Expiration).Sub(now); exp <= 0 {
+				continue
+			}
+		}
+		pipe.Set(rc.context(), rc.key(key), item.Value, exp)
+	}
+	if _, err := pipe.Exec(rc.context()); err != nil {
+		return loggermanager.Wrapf(err, loggermanager.CodeCacheUnavailable, "Error while storing the keys in redis").WithDetail("addr", rc.Addr)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package cachemanager
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -343,66 +344,35 @@ func TestRedisCache_Setup(t *testing.T) {
 
 // BENCHMARKS >>
 
-func BenchmarkMarshalString(b *testing.B) {
+func BenchmarkEncodeString(b *testing.B) {
 	s := `some string`
 	for i := 0; i < b.N; i++ {
-		_, _ = marshal(s)
+		_, _ = valueCodec{}.encode(s)
 	}
 }
 
-func BenchmarkMarshalWithTypeCheckString(b *testing.B) {
-	s := `some string`
-	for i := 0; i < b.N; i++ {
-		_, _ = marshalWithTypeCheck(s)
-	}
-}
-
-func BenchmarkMarshalBytes(b *testing.B) {
+func BenchmarkEncodeBytes(b *testing.B) {
 	s := []byte(`some string`)
 	for i := 0; i < b.N; i++ {
-		_, _ = marshal(s)
+		_, _ = valueCodec{}.encode(s)
 	}
 }
 
-func BenchmarkMarshalWithTypeCheckBytes(b *testing.B) {
-	s := []byte(`some string`)
-	for i := 0; i < b.N; i++ {
-		_, _ = marshalWithTypeCheck(s)
-	}
-}
-func BenchmarkMarshalGjsonVal(b *testing.B) {
+func BenchmarkEncodeGjsonVal(b *testing.B) {
 	s := gjson.Parse(`{"name":"testcase"}`).Value()
 	for i := 0; i < b.N; i++ {
-		_, _ = marshal(s)
+		_, _ = valueCodec{}.encode(s)
 	}
 }
 
-func BenchmarkMarshalWithTypeCheckGjsonVal(b *testing.B) {
-	s := gjson.Parse(`{"name":"testcase"}`).Value()
-	for i := 0; i < b.N; i++ {
-		_, _ = marshalWithTypeCheck(s)
-	}
-}
-
-func BenchmarkMarshalStruct(b *testing.B) {
-	type Struct struct {
-		Name string `json:"name"`
-	}
-
-	s := Struct{"test"}
-	for i := 0; i < b.N; i++ {
-		_, _ = marshal(s)
-	}
-}
-
-func BenchmarkMarshalWithTypeCheckStruct(b *testing.B) {
+func BenchmarkEncodeStruct(b *testing.B) {
 	type Struct struct {
 		Name string `json:"name"`
 	}
 
 	s := Struct{"test"}
 	for i := 0; i < b.N; i++ {
-		_, _ = marshalWithTypeCheck(s)
+		_, _ = valueCodec{}.encode(s)
 	}
 }
 
@@ -453,3 +423,88 @@ func TestRedisCache_GetAll(t *testing.T) {
 		})
 	}
 }
+
+func TestRedisCache_SaveLoadFile(t *testing.T) {
+	fname := t.TempDir() + "/redis.json"
+	rc := &RedisCache{}
+	rc.Setup("127.0.0.1:6379", "", "save", 0, time.Second*60)
+	rc.flushDB()
+	rc.Set("a", "x")
+	rc.SetNoExpiration("b", "y")
+	rc.SetWithExpiration("c", "z", time.Millisecond)
+	time.Sleep(time.Millisecond * 5)
+	if err := rc.SaveFile(fname); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &RedisCache{}
+	loaded.Setup("127.0.0.1:6379", "", "load", 0, time.Second*60)
+	if err := loaded.LoadFile(fname); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a": "x", "b": "y"}
+	for k, v := range want {
+		if got, ok := loaded.Get(k); !ok || got != v {
+			t.Errorf("Get(%s) = %v, %v, want %s", k, got, ok, v)
+		}
+	}
+	if _, ok := loaded.Get("c"); ok {
+		t.Error("expired key c was loaded")
+	}
+	if ttl := loaded.cli.PTTL(ctx, loaded.key("a")).Val(); ttl <= 0 || ttl > time.Second*60 {
+		t.Errorf("ttl of a = %v", ttl)
+	}
+}
+
+func TestRedisCache_Typed(t *testing.T) {
+	rc := &RedisCache{}
+	setup(rc)
+	tc := NewTypedCache[map[string]int](rc)
+	if err := tc.Set("counts", map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := tc.Get("counts")
+	if err != nil || got["a"] != 1 {
+		t.Errorf("Get(counts) = %v, %v", got, err)
+	}
+}
+
+func TestRedisCache_Codecs(t *testing.T) {
+	rc, err := SetupRedisCache(RedisWithAddr("127.0.0.1:6379"), RedisWithPrefix("codecs"), RedisWithExpiration(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.flushDB()
+	rc.Set("json", profile{Name: "ana"})
+	rc.cli.Set(ctx, rc.key("legacy"), `{"name":"bob"}`, time.Minute)
+
+	// values keep the codec they were stored with
+	rc.Codec = MsgpackCodec{}
+	rc.Compression = CompressionZstd
+	rc.CompressAbove = 64
+	rc.Set("msgpack", profile{Name: "eve", Roles: []string{strings.Repeat("role", 40)}})
+
+	for key, name := range map[string]string{"json": "ana", "legacy": "bob", "msgpack": "eve"} {
+		var p profile
+		if found, err := rc.GetInto(key, &p); !found || err != nil || p.Name != name {
+			t.Errorf("GetInto(%s) = %+v, %v, %v", key, p, found, err)
+		}
+	}
+	if found, err := rc.GetInto("missing", new(profile)); found || err != nil {
+		t.Errorf("GetInto(missing) = %v, %v", found, err)
+	}
+	if got, ok := rc.Get("json"); !ok || got != `{"name":"ana","roles":null}` {
+		t.Errorf("Get(json) = %v, %v", got, ok)
+	}
+	if all := rc.GetAll(); len(all) != 3 || all["msgpack"].(map[string]interface{})["name"] != "eve" {
+		t.Errorf("GetAll() = %v", all)
+	}
+
+	tc := NewTypedCache[profile](rc, WithCodec(GobCodec{}))
+	if err := tc.Set("gob", profile{Name: "joe"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := NewTypedCache[profile](rc).Get("gob"); err != nil || got.Name != "joe" {
+		t.Errorf("Get(gob) = %+v, %v", got, err)
+	}
+}
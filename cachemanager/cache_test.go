@@ -0,0 +1,170 @@
+package cachemanager
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crearosoft/corelib/loggermanager"
+)
+
+func TestNewCache(t *testing.T) {
+	c, err := NewCache(TypeCache, CacheOptions{Expiration: time.Minute, MaxEntries: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Type() != TypeCache {
+		t.Errorf("Type() = %d, want %d", c.Type(), TypeCache)
+	}
+	c.Set("a", 1)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %v, %v", v, ok)
+	}
+
+	c, err = NewCache(42, CacheOptions{})
+	if c != nil || !errors.Is(err, loggermanager.ErrInvalidArgument) {
+		t.Errorf("NewCache(42) = %v, %v", c, err)
+	}
+}
+
+func TestCacheHelper_SaveLoadFile(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "cache", "items.json")
+	var c Cache = SetupCache(WithExpiration(time.Minute))
+	c.Set("a", "x")
+	c.SetNoExpiration("b", 2.5)
+	if err := c.SaveFile(fname); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := SetupCache(WithExpiration(time.Minute))
+	if err := loaded.LoadFile(fname); err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.GetAll(); len(got) != 2 || got["a"] != "x" || got["b"] != 2.5 {
+		t.Errorf("loaded %v", got)
+	}
+
+	err := loaded.LoadFile(filepath.Join(t.TempDir(), "missing.json"))
+	if !errors.Is(err, loggermanager.ErrCacheFileReadFailed) {
+		t.Errorf("LoadFile(missing) = %v", err)
+	}
+}
+
+func TestCacheHelper_MaxEntries(t *testing.T) {
+	type eviction struct {
+		key    string
+		val    interface{}
+		reason EvictionReason
+	}
+	var evicted []eviction
+	c := SetupCache(WithMaxEntries(2), WithOnEvicted(func(key string, val interface{}, reason EvictionReason) {
+		evicted = append(evicted, eviction{key, val, reason})
+	}))
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+	c.Set("a", 10) // update, nothing evicted
+
+	if n := c.GetItemsCount(); n != 2 {
+		t.Errorf("GetItemsCount() = %d, want 2", n)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("least recently used b is still cached")
+	}
+	if len(evicted) != 1 || evicted[0] != (eviction{"b", 2, EvictedCapacity}) {
+		t.Errorf("evicted %v", evicted)
+	}
+	st := c.Stats()
+	if st.Items != 2 || st.Hits != 1 || st.Misses != 1 || st.Evictions != 1 {
+		t.Errorf("Stats() = %+v", st)
+	}
+
+	c.Delete("a")
+	c.Set("d", 4)
+	if len(evicted) != 1 {
+		t.Errorf("Delete made room yet evicted %v", evicted[1:])
+	}
+}
+
+func TestCacheHelper_ExpiredItemsReported(t *testing.T) {
+	var reasons []EvictionReason
+	c := SetupCache(WithMaxEntries(10), WithCleanupInterval(time.Millisecond), WithOnEvicted(func(_ string, _ interface{}, reason EvictionReason) {
+		reasons = append(reasons, reason)
+	}))
+	c.SetWithExpiration("short", 1, time.Millisecond)
+	c.SetNoExpiration("long", 2)
+
+	deadline := time.Now().Add(time.Second)
+	for c.Stats().Expirations == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if len(reasons) != 1 || reasons[0] != EvictedExpired {
+		t.Fatalf("reasons = %v", reasons)
+	}
+	if _, ok := c.Get("long"); !ok {
+		t.Error("long lost")
+	}
+	// the slot of the expired item is free again
+	for i := 0; i < 9; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+	if st := c.Stats(); st.Evictions != 0 || st.Items != 10 {
+		t.Errorf("Stats() = %+v", st)
+	}
+}
+
+func TestCacheHelper_LoadFileOverMaxEntries(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "items.json")
+	full := SetupCache()
+	for i := 0; i < 5; i++ {
+		full.Set(strconv.Itoa(i), i)
+	}
+	if err := full.SaveFile(fname); err != nil {
+		t.Fatal(err)
+	}
+
+	c := SetupCache(WithMaxEntries(3), WithEvictionPolicy(PolicyLFU))
+	if err := c.LoadFile(fname); err != nil {
+		t.Fatal(err)
+	}
+	if st := c.Stats(); st.Items != 3 || st.Evictions != 2 {
+		t.Errorf("Stats() = %+v", st)
+	}
+}
+
+func TestCacheHelper_MaxBytes(t *testing.T) {
+	c := SetupCache(WithMaxBytes(3000))
+	kb := strings.Repeat("x", 1000)
+	c.Set("a", kb)
+	c.Set("b", kb)
+	if st := c.Stats(); st.Items != 2 || st.Bytes != 2*itemSize("a", kb) {
+		t.Fatalf("Stats() = %+v", st)
+	}
+	c.Get("a")
+	c.Set("c", kb)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("least recently used b is still cached")
+	}
+	st := c.Stats()
+	if st.Items != 2 || st.Evictions != 1 || st.Bytes > 3000 {
+		t.Errorf("Stats() = %+v", st)
+	}
+
+	// an update shrinking a value frees its bytes
+	c.Set("a", "small")
+	c.Delete("c")
+	if st := c.Stats(); st.Bytes != itemSize("a", "small") {
+		t.Errorf("Bytes = %d, want %d", st.Bytes, itemSize("a", "small"))
+	}
+
+	// values over the budget are not kept
+	c.Set("huge", fixedSize{})
+	if _, ok := c.Get("huge"); ok || c.Stats().Bytes != 0 {
+		t.Errorf("huge value kept, Stats() = %+v", c.Stats())
+	}
+}
@@ -6,12 +6,14 @@ package cachemanager
  * @copyright Crearosoft
  */
 
-// Package cachemdl will help cache object into memory. It Uses LRU algo
+// Package cachemdl will help cache object into memory. It evicts with LRU, LFU or ARC once MaxEntries are held
 
 import (
 	"encoding/json"
 	"os"
 	"path"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/crearosoft/corelib/loggermanager"
@@ -24,7 +26,46 @@ type CacheHelper struct {
 	Cache       *cache.Cache
 	Expiration  time.Duration
 	CleanupTime time.Duration
-	MaxEntries  int
+	MaxEntries  int            // 0 is unbounded
+	MaxBytes    int64          // budget of the estimated item sizes, see Sizer, 0 is unbounded
+	Policy      EvictionPolicy // picks the items removed once MaxEntries or MaxBytes are held, default PolicyLRU
+
+	onEvicted func(key string, val interface{}, reason EvictionReason)
+
+	// set when tracked, i.e. bounded or with an eviction callback.
+	// Tracked caches serialise access with mu, go-cache calls collectRemoved for every deletion.
+	mu      sync.Mutex
+	keys    evictor
+	reasons map[string]EvictionReason // our own deletions in flight, others are expirations
+	sizes   map[string]int64          // with MaxBytes
+	bytes   int64
+
+	removedMu sync.Mutex
+	removed   []removedItem
+
+	hits, misses, evictions, expirations uint64
+}
+
+// removedItem is an item deleted from go-cache, collected until the next tracked operation
+type removedItem struct {
+	key string
+	val interface{}
+}
+
+// evictedItem is reported to the eviction callback
+type evictedItem struct {
+	removedItem
+	reason EvictionReason
+}
+
+// CacheStats are the counters of a CacheHelper since it was set up
+type CacheStats struct {
+	Items       int
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64 // items removed to stay within MaxEntries or MaxBytes
+	Expirations uint64 // expired items removed by the cleanup, counted when the cache is bounded or has an eviction callback
+	Bytes       int64  // estimated size of the items, with MaxBytes
 }
 
 type cacheOption func(*CacheHelper)
@@ -50,12 +91,36 @@ func WithCleanupInterval(ivl time.Duration) cacheOption {
 	}
 }
 
+// WithMaxBytes bounds the estimated size of the items, keys and bookkeeping included.
+// Values implementing Sizer report their own size.
+func WithMaxBytes(n int64) cacheOption {
+	return func(cfg *CacheHelper) {
+		cfg.MaxBytes = n
+	}
+}
+
+// WithEvictionPolicy selects the items removed once MaxEntries are held
+func WithEvictionPolicy(p EvictionPolicy) cacheOption {
+	return func(cfg *CacheHelper) {
+		cfg.Policy = p
+	}
+}
+
+// WithOnEvicted calls fn for items removed to stay within MaxEntries and for expired items removed by the cleanup.
+// Expired items are reported on the next cache operation. Deleted and purged items are not reported.
+func WithOnEvicted(fn func(key string, val interface{}, reason EvictionReason)) cacheOption {
+	return func(cfg *CacheHelper) {
+		cfg.onEvicted = fn
+	}
+}
+
 // Setup initializes fastcache cache for application. Must be called only once.
 func (cacheHelper *CacheHelper) Setup(maxEntries int, expiration time.Duration, cleanupTime time.Duration) {
 
 	cacheHelper.MaxEntries = maxEntries
 	cacheHelper.Expiration = expiration
-	cacheHelper.Cache = cache.New(cacheHelper.Expiration, cacheHelper.CleanupTime)
+	cacheHelper.CleanupTime = cleanupTime
+	cacheHelper.reset(nil)
 
 }
 
@@ -67,32 +132,206 @@ func SetupCache(opts ...cacheOption) *CacheHelper {
 		opts[i](fc)
 	}
 
-	fc.Cache = cache.New(fc.Expiration, fc.CleanupTime)
+	fc.reset(nil)
 	return fc
 }
 
-// SaveFile is the
-func (cacheHelper *CacheHelper) SaveFile(fname string) error {
+// reset replaces the underlying cache with one holding items
+func (cacheHelper *CacheHelper) reset(items map[string]cache.Item) {
+	cacheHelper.mu.Lock()
+	if items == nil {
+		items = make(map[string]cache.Item)
+	}
+	c := cache.NewFrom(cacheHelper.Expiration, cacheHelper.CleanupTime, items)
+	cacheHelper.Cache = c
+	cacheHelper.keys, cacheHelper.reasons, cacheHelper.sizes, cacheHelper.bytes = nil, nil, nil, 0
+	var evicted []evictedItem
+	if cacheHelper.MaxEntries > 0 || cacheHelper.MaxBytes > 0 || cacheHelper.onEvicted != nil {
+		cacheHelper.keys = newEvictor(cacheHelper.Policy, cacheHelper.MaxEntries)
+		cacheHelper.reasons = make(map[string]EvictionReason)
+		if cacheHelper.MaxBytes > 0 {
+			cacheHelper.sizes = make(map[string]int64)
+		}
+		c.OnEvicted(cacheHelper.collectRemoved)
+		// items of a file beyond the bounds
+		for k, item := range items {
+			cacheHelper.trackLocked(k, cacheHelper.sizeOf(k, item.Object))
+		}
+		evicted = cacheHelper.drainLocked()
+	}
+	cacheHelper.mu.Unlock()
+	cacheHelper.notify(evicted)
+}
+
+func (cacheHelper *CacheHelper) tracked() bool {
+	return cacheHelper.reasons != nil
+}
+
+// collectRemoved is the go-cache eviction callback. It may run in the janitor goroutine,
+// so it only collects the item for drainLocked.
+func (cacheHelper *CacheHelper) collectRemoved(key string, val interface{}) {
+	cacheHelper.removedMu.Lock()
+	cacheHelper.removed = append(cacheHelper.removed, removedItem{key: key, val: val})
+	cacheHelper.removedMu.Unlock()
+}
+
+// sizeOf estimates the item when the cache has MaxBytes
+func (cacheHelper *CacheHelper) sizeOf(key string, val interface{}) int64 {
+	if cacheHelper.MaxBytes <= 0 {
+		return 0
+	}
+	return itemSize(key, val)
+}
+
+// trackLocked records a write of key and evicts the items beyond MaxEntries and MaxBytes
+func (cacheHelper *CacheHelper) trackLocked(key string, size int64) {
+	if cacheHelper.keys.has(key) {
+		cacheHelper.keys.touch(key)
+	} else {
+		for _, victim := range cacheHelper.keys.add(key) {
+			cacheHelper.evictLocked(victim)
+		}
+	}
+	if cacheHelper.sizes == nil {
+		return
+	}
+	cacheHelper.bytes += size - cacheHelper.sizes[key]
+	cacheHelper.sizes[key] = size
+	for cacheHelper.bytes > cacheHelper.MaxBytes {
+		victim := cacheHelper.keys.evict()
+		if victim == "" {
+			break
+		}
+		cacheHelper.evictLocked(victim)
+	}
+}
+
+// evictLocked deletes a key the evictor gave up
+func (cacheHelper *CacheHelper) evictLocked(key string) {
+	cacheHelper.forgetLocked(key)
+	cacheHelper.reasons[key] = EvictedCapacity
+	cacheHelper.Cache.Delete(key)
+}
+
+// forgetLocked drops the size of key
+func (cacheHelper *CacheHelper) forgetLocked(key string) {
+	if cacheHelper.sizes != nil {
+		cacheHelper.bytes -= cacheHelper.sizes[key]
+		delete(cacheHelper.sizes, key)
+	}
+}
+
+// drainLocked sorts the items removed from go-cache since the last call into
+// our own deletions and expirations, and returns the ones to report
+func (cacheHelper *CacheHelper) drainLocked() []evictedItem {
+	cacheHelper.removedMu.Lock()
+	removed := cacheHelper.removed
+	cacheHelper.removed = nil
+	cacheHelper.removedMu.Unlock()
+
+	var evicted []evictedItem
+	for _, r := range removed {
+		reason, ours := cacheHelper.reasons[r.key]
+		if !ours {
+			if !cacheHelper.keys.has(r.key) {
+				// deleted by Delete
+				continue
+			}
+			if _, found := cacheHelper.Cache.Get(r.key); found {
+				// set again after expiring
+				continue
+			}
+			cacheHelper.keys.remove(r.key)
+			cacheHelper.forgetLocked(r.key)
+			reason = EvictedExpired
+		}
+		delete(cacheHelper.reasons, r.key)
+		if reason == EvictedCapacity {
+			atomic.AddUint64(&cacheHelper.evictions, 1)
+		} else {
+			atomic.AddUint64(&cacheHelper.expirations, 1)
+		}
+		evicted = append(evicted, evictedItem{removedItem: r, reason: reason})
+	}
+	return evicted
+}
+
+// notify calls the eviction callback, outside of mu so it may use the cache
+func (cacheHelper *CacheHelper) notify(evicted []evictedItem) {
+	if cacheHelper.onEvicted == nil {
+		return
+	}
+	for _, e := range evicted {
+		cacheHelper.onEvicted(e.key, e.val, e.reason)
+	}
+}
+
+// set stores object and evicts the items beyond MaxEntries
+func (cacheHelper *CacheHelper) set(key string, object interface{}, duration time.Duration) {
+	if !cacheHelper.tracked() {
+		cacheHelper.Cache.Set(key, object, duration)
+		return
+	}
+	size := cacheHelper.sizeOf(key, object)
+	cacheHelper.mu.Lock()
+	cacheHelper.Cache.Set(key, object, duration)
+	cacheHelper.trackLocked(key, size)
+	evicted := cacheHelper.drainLocked()
+	cacheHelper.mu.Unlock()
+	cacheHelper.notify(evicted)
+}
+
+// Stats returns the counters of the cache
+func (cacheHelper *CacheHelper) Stats() CacheStats {
+	var bytes int64
+	if cacheHelper.tracked() {
+		cacheHelper.mu.Lock()
+		evicted := cacheHelper.drainLocked()
+		bytes = cacheHelper.bytes
+		cacheHelper.mu.Unlock()
+		cacheHelper.notify(evicted)
+	}
+	return CacheStats{
+		Items:       cacheHelper.Cache.ItemCount(),
+		Hits:        atomic.LoadUint64(&cacheHelper.hits),
+		Misses:      atomic.LoadUint64(&cacheHelper.misses),
+		Evictions:   atomic.LoadUint64(&cacheHelper.evictions),
+		Expirations: atomic.LoadUint64(&cacheHelper.expirations),
+		Bytes:       bytes,
+	}
+}
+
+// createFile truncates fname, creating its directory when missing
+func createFile(fname string) (*os.File, error) {
 	_, err := os.Stat(fname)
 	if os.IsNotExist(err) {
 		dir, _ := path.Split(fname)
 		err := os.MkdirAll(dir, 0755)
 		if err != nil {
-			panic(err)
+			return nil, loggermanager.Wrapf(err, loggermanager.CodeCacheFileWriteFailed, "Error while creating the directory").WithDetail("file", fname)
 		}
 	}
-	f, err := os.OpenFile(fname, os.O_TRUNC|os.O_CREATE, 0777)
+	f, err := os.OpenFile(fname, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0777)
+	if err != nil {
+		return nil, loggermanager.Wrapf(err, loggermanager.CodeCacheFileWriteFailed, "Error while opening the file").WithDetail("file", fname)
+	}
+	return f, nil
+}
+
+// SaveFile writes the items of the cache to fname as JSON, see LoadFile
+func (cacheHelper *CacheHelper) SaveFile(fname string) error {
+	f, err := createFile(fname)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer f.Close()
 	itm := cacheHelper.GetItems()
 	b, err := json.Marshal(itm)
 	if err != nil {
-		return loggermanager.Wrap("Error while marshalling the data")
+		return loggermanager.Wrapf(err, loggermanager.CodeCacheMarshalFailed, "Error while marshalling the data")
 	}
 	if _, err = f.Write(b); err != nil {
-		return loggermanager.Wrap("Error while writing the data to file")
+		return loggermanager.Wrapf(err, loggermanager.CodeCacheFileWriteFailed, "Error while writing the data to file").WithDetail("file", fname)
 	}
 	return nil
 }
@@ -104,7 +343,7 @@ func (cacheHelper *CacheHelper) LoadFile(fname string) error {
 
 	defer file.Close()
 	if err != nil {
-		return loggermanager.Wrap("Error while reading file")
+		return loggermanager.Wrapf(err, loggermanager.CodeCacheFileReadFailed, "Error while reading file").WithDetail("file", fname)
 	}
 	// buffer := make([]byte, BufferSize)
 	// // bytesread
@@ -125,14 +364,12 @@ func (cacheHelper *CacheHelper) LoadFile(fname string) error {
 	// if err := json.Unmarshal(buffer, &itm); err != nil {
 	if err = dec.Decode(&itm); err != nil {
 		// log.Fatal(err)
-		nc := cache.New(cacheHelper.Expiration, cacheHelper.CleanupTime)
-		cacheHelper.Cache = nc
-		loggermanager.LogError("Error while binding the data from file")
-		return loggermanager.Wrap("Error while binding the data from file")
+		cacheHelper.reset(nil)
+		logger.Error("Error while binding the data from file", "file", fname, loggermanager.Err(err))
+		return loggermanager.Wrapf(err, loggermanager.CodeCacheFileDecodeFailed, "Error while binding the data from file").WithDetail("file", fname)
 	}
 
-	nc := cache.NewFrom(cacheHelper.Expiration, cacheHelper.CleanupTime, itm)
-	cacheHelper.Cache = nc
+	cacheHelper.reset(itm)
 	// fmt.Println(string(buffer))
 	// cacheHelper.Cache.Load
 	return nil
@@ -140,7 +377,27 @@ func (cacheHelper *CacheHelper) LoadFile(fname string) error {
 
 // Get -
 func (cacheHelper *CacheHelper) Get(key string) (interface{}, bool) {
-	return cacheHelper.Cache.Get(key)
+	if !cacheHelper.tracked() {
+		return cacheHelper.count(cacheHelper.Cache.Get(key))
+	}
+	cacheHelper.mu.Lock()
+	val, found := cacheHelper.Cache.Get(key)
+	if found {
+		cacheHelper.keys.touch(key)
+	}
+	evicted := cacheHelper.drainLocked()
+	cacheHelper.mu.Unlock()
+	cacheHelper.notify(evicted)
+	return cacheHelper.count(val, found)
+}
+
+func (cacheHelper *CacheHelper) count(val interface{}, found bool) (interface{}, bool) {
+	if found {
+		atomic.AddUint64(&cacheHelper.hits, 1)
+	} else {
+		atomic.AddUint64(&cacheHelper.misses, 1)
+	}
+	return val, found
 }
 
 // GetItems -
@@ -150,27 +407,53 @@ func (cacheHelper *CacheHelper) GetItems() map[string]cache.Item {
 
 // SetNoExpiration -
 func (cacheHelper *CacheHelper) SetNoExpiration(key string, object interface{}) {
-	cacheHelper.Cache.Set(key, object, cache.NoExpiration)
+	cacheHelper.set(key, object, cache.NoExpiration)
 }
 
 // Set -
 func (cacheHelper *CacheHelper) Set(key string, object interface{}) {
-	cacheHelper.Cache.Set(key, object, cacheHelper.Expiration)
+	cacheHelper.set(key, object, cacheHelper.Expiration)
 }
 
 // SetWithExpiration -
 func (cacheHelper *CacheHelper) SetWithExpiration(key string, object interface{}, duration time.Duration) {
-	cacheHelper.Cache.Set(key, object, duration)
+	cacheHelper.set(key, object, duration)
 }
 
 // Purge -
 func (cacheHelper *CacheHelper) Purge() {
+	if !cacheHelper.tracked() {
+		cacheHelper.Cache.Flush()
+		return
+	}
+	cacheHelper.mu.Lock()
 	cacheHelper.Cache.Flush()
+	cacheHelper.keys = newEvictor(cacheHelper.Policy, cacheHelper.MaxEntries)
+	for k := range cacheHelper.reasons {
+		delete(cacheHelper.reasons, k)
+	}
+	if cacheHelper.sizes != nil {
+		cacheHelper.sizes, cacheHelper.bytes = make(map[string]int64), 0
+	}
+	cacheHelper.removedMu.Lock()
+	cacheHelper.removed = nil
+	cacheHelper.removedMu.Unlock()
+	cacheHelper.mu.Unlock()
 }
 
 // Delete -
 func (cacheHelper *CacheHelper) Delete(key string) {
+	if !cacheHelper.tracked() {
+		cacheHelper.Cache.Delete(key)
+		return
+	}
+	cacheHelper.mu.Lock()
+	cacheHelper.keys.remove(key)
+	cacheHelper.forgetLocked(key)
 	cacheHelper.Cache.Delete(key)
+	evicted := cacheHelper.drainLocked()
+	cacheHelper.mu.Unlock()
+	cacheHelper.notify(evicted)
 }
 
 // GetItemsCount : Number of items in the cache
@@ -0,0 +1,309 @@
+package cachemanager
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec encodes the values of caches storing bytes, such as RedisCache
+type Codec interface {
+	// ID identifies the codec in the header of stored values, so values stay readable after
+	// the codec of a cache changes. IDs below 16 are reserved, see RegisterCodec.
+	ID() byte
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// IDs of the built in codecs
+const (
+	codecRaw     byte = 0 // strings and bytes stored as given
+	CodecJSON    byte = 1
+	CodecMsgpack byte = 2
+	CodecGob     byte = 3
+	CodecProto   byte = 4
+)
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[byte]Codec{}
+)
+
+func init() {
+	for _, c := range []Codec{JSONCodec{}, MsgpackCodec{}, GobCodec{}, ProtoCodec{}} {
+		codecs[c.ID()] = c
+	}
+}
+
+// RegisterCodec makes values stored by a custom codec readable, its ID must be 16 or above
+func RegisterCodec(c Codec) error {
+	if c.ID() < 16 {
+		return fmt.Errorf("codec id %d is reserved", c.ID())
+	}
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[c.ID()] = c
+	return nil
+}
+
+func codecByID(id byte) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[id]
+	return c, ok
+}
+
+// JSONCodec encodes values as JSON, the default
+type JSONCodec struct{}
+
+// ID -
+func (JSONCodec) ID() byte { return CodecJSON }
+
+// Marshal -
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal -
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// MsgpackCodec encodes values as MessagePack, smaller and faster than JSON and keeping integer types.
+// Struct fields are named by their json tags, like with JSONCodec.
+type MsgpackCodec struct{}
+
+// ID -
+func (MsgpackCodec) ID() byte { return CodecMsgpack }
+
+// Marshal -
+func (MsgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal -
+func (MsgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// GobCodec encodes values with encoding/gob. Values can not be decoded into interface{},
+// so GetAll returns them encoded.
+type GobCodec struct{}
+
+// ID -
+func (GobCodec) ID() byte { return CodecGob }
+
+// Marshal -
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal -
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// ProtoCodec encodes protocol buffer messages. Values must implement proto.Message,
+// they are decoded into a message or a pointer to a message pointer.
+type ProtoCodec struct{}
+
+// ID -
+func (ProtoCodec) ID() byte { return CodecProto }
+
+// Marshal -
+func (ProtoCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("proto codec: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+// Unmarshal -
+func (ProtoCodec) Unmarshal(data []byte, v interface{}) error {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, m)
+	}
+	// e.g. **pb.User of a TypedCache[*pb.User]
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Kind() == reflect.Ptr {
+		if rv.Elem().IsNil() {
+			rv.Elem().Set(reflect.New(rv.Elem().Type().Elem()))
+		}
+		if m, ok := rv.Elem().Interface().(proto.Message); ok {
+			return proto.Unmarshal(data, m)
+		}
+	}
+	return fmt.Errorf("proto codec: can not decode into %T", v)
+}
+
+// Compression of stored values
+type Compression byte
+
+// Compressions of RedisCache values
+const (
+	CompressionNone Compression = iota
+	CompressionZstd
+	CompressionSnappy
+)
+
+// defaultCompressAbove is the payload size from which values are compressed
+const defaultCompressAbove = 1024
+
+var (
+	zstdOnce sync.Once
+	zstdEnc  *zstd.Encoder
+	zstdDec  *zstd.Decoder
+)
+
+func zstdCoders() (*zstd.Encoder, *zstd.Decoder) {
+	zstdOnce.Do(func() {
+		zstdEnc, _ = zstd.NewWriter(nil)
+		zstdDec, _ = zstd.NewReader(nil)
+	})
+	return zstdEnc, zstdDec
+}
+
+func compress(c Compression, data []byte) ([]byte, error) {
+	switch c {
+	case CompressionZstd:
+		enc, _ := zstdCoders()
+		return enc.EncodeAll(data, nil), nil
+	case CompressionSnappy:
+		return snappy.Encode(nil, data), nil
+	case CompressionNone:
+		return data, nil
+	}
+	return nil, fmt.Errorf("unknown compression %d", c)
+}
+
+func decompress(c Compression, data []byte) ([]byte, error) {
+	switch c {
+	case CompressionZstd:
+		_, dec := zstdCoders()
+		return dec.DecodeAll(data, nil)
+	case CompressionSnappy:
+		return snappy.Decode(nil, data)
+	case CompressionNone:
+		return data, nil
+	}
+	return nil, fmt.Errorf("unknown compression %d", c)
+}
+
+// Values encoded by a codec or compressed start with valueMagic, the codec id and the compression.
+// Strings and bytes are stored without it, like older releases stored them, so other clients
+// can read them. Values without it are strings, bytes or JSON written by older releases.
+var valueMagic = []byte{0x00, 0xce}
+
+const valueHeaderLen = 4
+
+// encodedValue is a value already encoded with its header, stored as given
+type encodedValue []byte
+
+// valueCodec encodes values for a cache storing bytes
+type valueCodec struct {
+	codec         Codec
+	compression   Compression
+	compressAbove int
+}
+
+// encode returns val with its header. Strings and bytes are stored as given unless compressed
+// or starting with valueMagic.
+func (vc valueCodec) encode(val interface{}) ([]byte, error) {
+	if d, ok := val.(encodedValue); ok {
+		return d, nil
+	}
+	id := codecRaw
+	var payload []byte
+	switch d := val.(type) {
+	case string:
+		payload = []byte(d)
+	case []byte:
+		payload = d
+	default:
+		codec := vc.codec
+		if codec == nil {
+			codec = JSONCodec{}
+		}
+		var err error
+		if payload, err = codec.Marshal(val); err != nil {
+			return nil, err
+		}
+		id = codec.ID()
+	}
+	c := CompressionNone
+	above := vc.compressAbove
+	if above <= 0 {
+		above = defaultCompressAbove
+	}
+	if vc.compression != CompressionNone && len(payload) >= above {
+		compressed, err := compress(vc.compression, payload)
+		if err != nil {
+			return nil, err
+		}
+		payload, c = compressed, vc.compression
+	}
+	if id == codecRaw && c == CompressionNone && !bytes.HasPrefix(payload, valueMagic) {
+		return payload, nil
+	}
+	out := make([]byte, valueHeaderLen, valueHeaderLen+len(payload))
+	copy(out, valueMagic)
+	out[2], out[3] = id, byte(c)
+	return append(out, payload...), nil
+}
+
+// decodeValue returns the codec id and the uncompressed payload of a stored value,
+// legacy values have the id codecRaw
+func decodeValue(data []byte) (byte, []byte, error) {
+	if len(data) < valueHeaderLen || !bytes.HasPrefix(data, valueMagic) {
+		return codecRaw, data, nil
+	}
+	payload, err := decompress(Compression(data[3]), data[valueHeaderLen:])
+	return data[2], payload, err
+}
+
+// unmarshalValue decodes a stored value into v with the codec it was written with.
+// Raw values are assigned to strings and bytes, otherwise decoded as JSON like older releases did.
+func unmarshalValue(data []byte, v interface{}) error {
+	id, payload, err := decodeValue(data)
+	if err != nil {
+		return err
+	}
+	if id == codecRaw {
+		switch p := v.(type) {
+		case *string:
+			*p = string(payload)
+			return nil
+		case *[]byte:
+			// payload may be shared with a cached copy
+			*p = append([]byte(nil), payload...)
+			return nil
+		}
+		return json.Unmarshal(payload, v)
+	}
+	codec, ok := codecByID(id)
+	if !ok {
+		return fmt.Errorf("value written by unknown codec %d", id)
+	}
+	return codec.Unmarshal(payload, v)
+}
@@ -0,0 +1,123 @@
+package cachemanager
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCodecs(t *testing.T) {
+	want := profile{Name: "ana", Roles: []string{"admin", "dev"}}
+	for _, c := range []Codec{JSONCodec{}, MsgpackCodec{}, GobCodec{}} {
+		b, err := valueCodec{codec: c}.encode(want)
+		if err != nil {
+			t.Fatalf("codec %d: %v", c.ID(), err)
+		}
+		if b[2] != c.ID() {
+			t.Errorf("codec %d: header names codec %d", c.ID(), b[2])
+		}
+		var got profile
+		if err := unmarshalValue(b, &got); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("codec %d: decoded %+v, %v", c.ID(), got, err)
+		}
+	}
+}
+
+func TestProtoCodec(t *testing.T) {
+	b, err := valueCodec{codec: ProtoCodec{}}.encode(wrapperspb.String("ana"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got *wrapperspb.StringValue
+	if err := unmarshalValue(b, &got); err != nil || got.GetValue() != "ana" {
+		t.Errorf("decoded %v, %v", got, err)
+	}
+	if _, err := (valueCodec{codec: ProtoCodec{}}).encode(profile{}); err == nil {
+		t.Error("encoded a value which is not a proto.Message")
+	}
+}
+
+func TestValueCompression(t *testing.T) {
+	long := strings.Repeat("cache ", 400)
+	for _, c := range []Compression{CompressionZstd, CompressionSnappy} {
+		vc := valueCodec{compression: c, compressAbove: 100}
+		b, err := vc.encode(long)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if Compression(b[3]) != c || len(b) >= len(long) {
+			t.Errorf("compression %d: header %v, %d bytes", c, b[:valueHeaderLen], len(b))
+		}
+		var got string
+		if err := unmarshalValue(b, &got); err != nil || got != long {
+			t.Errorf("compression %d: decoded %d bytes, %v", c, len(got), err)
+		}
+
+		if short, _ := vc.encode("short"); string(short) != "short" {
+			t.Errorf("compression %d: stored %q below the threshold", c, short)
+		}
+	}
+}
+
+func TestRawValues(t *testing.T) {
+	for _, val := range []interface{}{"plain", []byte("plain")} {
+		if b, _ := (valueCodec{codec: MsgpackCodec{}}).encode(val); string(b) != "plain" {
+			t.Errorf("encode(%T) = %q, want it stored as given", val, b)
+		}
+	}
+	// raw bytes looking like a header keep one
+	magic := []byte{0x00, 0xce, 0x01, 0x00, 'x'}
+	b, _ := valueCodec{}.encode(magic)
+	var got []byte
+	if err := unmarshalValue(b, &got); err != nil || !bytes.Equal(got, magic) {
+		t.Errorf("decoded %q, %v", got, err)
+	}
+	got[0] = 'y'
+	if b[valueHeaderLen] != 0x00 {
+		t.Error("decoded bytes share the stored value")
+	}
+}
+
+func TestLegacyValues(t *testing.T) {
+	var s string
+	if err := unmarshalValue([]byte("plain"), &s); err != nil || s != "plain" {
+		t.Errorf("string = %q, %v", s, err)
+	}
+	var p profile
+	if err := unmarshalValue([]byte(`{"name":"ana"}`), &p); err != nil || p.Name != "ana" {
+		t.Errorf("profile = %+v, %v", p, err)
+	}
+	if id, payload, _ := decodeValue([]byte("x")); id != codecRaw || !bytes.Equal(payload, []byte("x")) {
+		t.Errorf("decodeValue(x) = %d, %q", id, payload)
+	}
+}
+
+type upperCodec struct{}
+
+func (upperCodec) ID() byte { return 200 }
+
+func (upperCodec) Marshal(v interface{}) ([]byte, error) {
+	return []byte(strings.ToUpper(v.(profile).Name)), nil
+}
+
+func (upperCodec) Unmarshal(data []byte, v interface{}) error {
+	v.(*profile).Name = string(data)
+	return nil
+}
+
+func TestRegisterCodec(t *testing.T) {
+	if err := RegisterCodec(upperCodec{}); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := valueCodec{codec: upperCodec{}}.encode(profile{Name: "ana"})
+	var p profile
+	if err := unmarshalValue(b, &p); err != nil || p.Name != "ANA" {
+		t.Errorf("profile = %+v, %v", p, err)
+	}
+	if err := RegisterCodec(JSONCodec{}); err == nil {
+		t.Error("registered a reserved codec id")
+	}
+}
@@ -0,0 +1,354 @@
+package cachemanager
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// EvictionPolicy selects the items a CacheHelper removes once it holds MaxEntries or MaxBytes
+type EvictionPolicy int
+
+const (
+	// PolicyLRU evicts the least recently used item
+	PolicyLRU EvictionPolicy = iota
+	// PolicyLFU evicts the least frequently used item, the oldest one on ties
+	PolicyLFU
+	// PolicyARC balances recency and frequency with the adaptive replacement cache algorithm.
+	// It needs MaxEntries, PolicyLRU is used without.
+	PolicyARC
+)
+
+// EvictionReason tells why an item left a CacheHelper
+type EvictionReason int
+
+const (
+	// EvictedCapacity items were removed to stay within MaxEntries or MaxBytes
+	EvictedCapacity EvictionReason = iota + 1
+	// EvictedExpired items were removed by the cleanup after expiring
+	EvictedExpired
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictedCapacity:
+		return "capacity"
+	case EvictedExpired:
+		return "expired"
+	}
+	return "unknown"
+}
+
+// evictor tracks the keys of a cache and picks the ones to evict
+type evictor interface {
+	// add tracks a new key and returns the keys evicted to make room for it, which are no longer tracked
+	add(key string) []string
+	// evict stops tracking the next victim and returns it, "" if nothing is tracked
+	evict() string
+	// touch records a read or an update of a tracked key
+	touch(key string)
+	remove(key string)
+	has(key string) bool
+	len() int
+}
+
+// newEvictor returns the evictor of p, capacity 0 never evicts
+func newEvictor(p EvictionPolicy, capacity int) evictor {
+	switch p {
+	case PolicyLFU:
+		return &lfuEvictor{capacity: capacity, items: make(map[string]*lfuEntry)}
+	case PolicyARC:
+		if capacity > 0 {
+			return newARCEvictor(capacity)
+		}
+	}
+	return &lruEvictor{capacity: capacity, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+type lruEvictor struct {
+	capacity int
+	ll       *list.List // front is the most recently used
+	items    map[string]*list.Element
+}
+
+func (e *lruEvictor) add(key string) []string {
+	e.items[key] = e.ll.PushFront(key)
+	var evicted []string
+	for e.capacity > 0 && e.ll.Len() > e.capacity {
+		evicted = append(evicted, e.evict())
+	}
+	return evicted
+}
+
+func (e *lruEvictor) evict() string {
+	oldest := e.ll.Back()
+	if oldest == nil {
+		return ""
+	}
+	e.ll.Remove(oldest)
+	key := oldest.Value.(string)
+	delete(e.items, key)
+	return key
+}
+
+func (e *lruEvictor) touch(key string) {
+	if el, ok := e.items[key]; ok {
+		e.ll.MoveToFront(el)
+	}
+}
+
+func (e *lruEvictor) remove(key string) {
+	if el, ok := e.items[key]; ok {
+		e.ll.Remove(el)
+		delete(e.items, key)
+	}
+}
+
+func (e *lruEvictor) has(key string) bool {
+	_, ok := e.items[key]
+	return ok
+}
+
+func (e *lruEvictor) len() int {
+	return e.ll.Len()
+}
+
+type lfuEntry struct {
+	key   string
+	freq  uint64
+	tick  uint64 // last use, breaks ties between equal frequencies
+	index int
+}
+
+// lfuEvictor keeps a min heap ordered by frequency, then by last use
+type lfuEvictor struct {
+	capacity int
+	heap     lfuHeap
+	items    map[string]*lfuEntry
+	tick     uint64
+}
+
+func (e *lfuEvictor) add(key string) []string {
+	var evicted []string
+	for e.capacity > 0 && len(e.heap) >= e.capacity {
+		evicted = append(evicted, e.evict())
+	}
+	e.tick++
+	ent := &lfuEntry{key: key, freq: 1, tick: e.tick}
+	e.items[key] = ent
+	heap.Push(&e.heap, ent)
+	return evicted
+}
+
+func (e *lfuEvictor) evict() string {
+	if len(e.heap) == 0 {
+		return ""
+	}
+	victim := heap.Pop(&e.heap).(*lfuEntry)
+	delete(e.items, victim.key)
+	return victim.key
+}
+
+func (e *lfuEvictor) touch(key string) {
+	if ent, ok := e.items[key]; ok {
+		e.tick++
+		ent.freq++
+		ent.tick = e.tick
+		heap.Fix(&e.heap, ent.index)
+	}
+}
+
+func (e *lfuEvictor) remove(key string) {
+	if ent, ok := e.items[key]; ok {
+		heap.Remove(&e.heap, ent.index)
+		delete(e.items, key)
+	}
+}
+
+func (e *lfuEvictor) has(key string) bool {
+	_, ok := e.items[key]
+	return ok
+}
+
+func (e *lfuEvictor) len() int {
+	return len(e.heap)
+}
+
+type lfuHeap []*lfuEntry
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].tick < h[j].tick
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	ent := x.(*lfuEntry)
+	ent.index = len(*h)
+	*h = append(*h, ent)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	ent := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return ent
+}
+
+// arcEvictor implements the adaptive replacement cache of Megiddo and Modha.
+// t1 holds keys seen once, t2 keys seen at least twice, b1 and b2 remember keys recently
+// evicted from them. p is the adapting target size of t1.
+type arcEvictor struct {
+	capacity       int
+	p              int
+	t1, t2, b1, b2 *arcList
+	items          map[string]*arcEntry
+}
+
+type arcEntry struct {
+	el   *list.Element
+	list *arcList
+}
+
+type arcList struct {
+	ll *list.List // front is the most recently used
+}
+
+func newARCEvictor(capacity int) *arcEvictor {
+	return &arcEvictor{
+		capacity: capacity,
+		t1:       &arcList{ll: list.New()},
+		t2:       &arcList{ll: list.New()},
+		b1:       &arcList{ll: list.New()},
+		b2:       &arcList{ll: list.New()},
+		items:    make(map[string]*arcEntry),
+	}
+}
+
+func (e *arcEvictor) add(key string) []string {
+	var evicted []string
+	ent, known := e.items[key]
+	switch {
+	case known && ent.list == e.b1:
+		// recently evicted after one use, favour recency
+		e.p = minInt(e.capacity, e.p+maxInt(e.b2.ll.Len()/e.b1.ll.Len(), 1))
+		evicted = e.replace(false)
+		e.move(key, e.t2)
+	case known && ent.list == e.b2:
+		// recently evicted after several uses, favour frequency
+		e.p = maxInt(0, e.p-maxInt(e.b1.ll.Len()/e.b2.ll.Len(), 1))
+		evicted = e.replace(true)
+		e.move(key, e.t2)
+	default:
+		t1, b1 := e.t1.ll.Len(), e.b1.ll.Len()
+		total := t1 + b1 + e.t2.ll.Len() + e.b2.ll.Len()
+		switch {
+		case t1+b1 >= e.capacity:
+			if t1 < e.capacity {
+				e.drop(e.b1)
+				evicted = e.replace(false)
+			} else {
+				evicted = []string{e.drop(e.t1)}
+			}
+		case total >= e.capacity:
+			if total >= 2*e.capacity {
+				e.drop(e.b2)
+			}
+			evicted = e.replace(false)
+		}
+		e.move(key, e.t1)
+	}
+	return evicted
+}
+
+// replace moves the least recently used key of t1 or t2 to its ghost list when the cache is full
+func (e *arcEvictor) replace(inB2 bool) []string {
+	if e.len() < e.capacity {
+		return nil
+	}
+	return []string{e.victim(inB2)}
+}
+
+// victim demotes the key chosen by replace
+func (e *arcEvictor) victim(inB2 bool) string {
+	t1 := e.t1.ll.Len()
+	if t1 > 0 && (t1 > e.p || (inB2 && t1 == e.p) || e.t2.ll.Len() == 0) {
+		return e.demote(e.t1, e.b1)
+	}
+	return e.demote(e.t2, e.b2)
+}
+
+func (e *arcEvictor) evict() string {
+	if e.len() == 0 {
+		return ""
+	}
+	return e.victim(false)
+}
+
+func (e *arcEvictor) demote(from, to *arcList) string {
+	key := from.ll.Back().Value.(string)
+	e.move(key, to)
+	return key
+}
+
+// drop forgets the least recently used key of l
+func (e *arcEvictor) drop(l *arcList) string {
+	el := l.ll.Back()
+	key := el.Value.(string)
+	l.ll.Remove(el)
+	delete(e.items, key)
+	return key
+}
+
+// move makes key the most recently used of l
+func (e *arcEvictor) move(key string, l *arcList) {
+	if ent, ok := e.items[key]; ok {
+		ent.list.ll.Remove(ent.el)
+	}
+	e.items[key] = &arcEntry{el: l.ll.PushFront(key), list: l}
+}
+
+func (e *arcEvictor) touch(key string) {
+	if e.has(key) {
+		e.move(key, e.t2)
+	}
+}
+
+func (e *arcEvictor) remove(key string) {
+	if ent, ok := e.items[key]; ok && (ent.list == e.t1 || ent.list == e.t2) {
+		ent.list.ll.Remove(ent.el)
+		delete(e.items, key)
+	}
+}
+
+func (e *arcEvictor) has(key string) bool {
+	ent, ok := e.items[key]
+	return ok && (ent.list == e.t1 || ent.list == e.t2)
+}
+
+func (e *arcEvictor) len() int {
+	return e.t1.ll.Len() + e.t2.ll.Len()
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,86 @@
+package cachemanager
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestEvictors(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy EvictionPolicy
+		// on a cache of 3: add a, b, c, read a twice and b once, then add d and e
+		want []string
+	}{
+		{"LRU", PolicyLRU, []string{"c", "b"}},
+		{"LFU", PolicyLFU, []string{"c", "d"}},
+		{"ARC", PolicyARC, []string{"c", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newEvictor(tt.policy, 3)
+			var evicted []string
+			for _, k := range []string{"a", "b", "c"} {
+				evicted = append(evicted, e.add(k)...)
+			}
+			e.touch("a")
+			e.touch("b")
+			e.touch("a")
+			for _, k := range []string{"d", "e"} {
+				evicted = append(evicted, e.add(k)...)
+			}
+			if !reflect.DeepEqual(evicted, tt.want) {
+				t.Errorf("evicted %v, want %v", evicted, tt.want)
+			}
+			if e.len() != 3 || !e.has("e") {
+				t.Errorf("len() = %d, has(e) = %v", e.len(), e.has("e"))
+			}
+		})
+	}
+}
+
+func TestEvictorsUnbounded(t *testing.T) {
+	for _, p := range []EvictionPolicy{PolicyLRU, PolicyLFU, PolicyARC} {
+		e := newEvictor(p, 0)
+		for i := 0; i < 100; i++ {
+			if evicted := e.add(strconv.Itoa(i)); len(evicted) > 0 {
+				t.Fatalf("policy %d evicted %v", p, evicted)
+			}
+		}
+	}
+}
+
+func TestARCScanResistance(t *testing.T) {
+	e := newEvictor(PolicyARC, 4)
+	// a hot working set used twice
+	for _, k := range []string{"h1", "h2", "h1", "h2"} {
+		if e.has(k) {
+			e.touch(k)
+		} else {
+			e.add(k)
+		}
+	}
+	// a scan of keys used once must not push out the hot keys
+	for i := 0; i < 20; i++ {
+		e.add("scan" + strconv.Itoa(i))
+	}
+	if !e.has("h1") || !e.has("h2") {
+		t.Error("scan evicted the frequently used keys")
+	}
+	if e.len() != 4 {
+		t.Errorf("len() = %d, want 4", e.len())
+	}
+}
+
+func TestEvictorRemove(t *testing.T) {
+	for _, p := range []EvictionPolicy{PolicyLRU, PolicyLFU, PolicyARC} {
+		e := newEvictor(p, 2)
+		e.add("a")
+		e.add("b")
+		e.remove("a")
+		if evicted := e.add("c"); len(evicted) != 0 || e.has("a") || e.len() != 2 {
+			t.Errorf("policy %d: evicted %v after remove, len %d", p, evicted, e.len())
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package cachemanager
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/crearosoft/corelib/loggermanager"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// defaultL1Expiration is how long a LayeredCache keeps values read from redis in process
+const defaultL1Expiration = 10 * time.Second
+
+// LayeredCache reads through an in process CacheHelper (L1) to a RedisCache (L2).
+// Values read from L2 are kept in L1 for a short time. Set, Delete and Purge write to L2 and publish
+// an invalidation on a redis channel, so every LayeredCache sharing it evicts its L1 copy.
+//
+// An L1 copy may be stale for up to the L1 expiration if an invalidation is lost, e.g. while
+// the connection to redis is down. L1 must not be used by anything else.
+type LayeredCache struct {
+	l1      *CacheHelper
+	l2      *RedisCache
+	l1Exp   time.Duration
+	channel string
+	origin  string // identifies the invalidations of this instance
+
+	mu        sync.Mutex          // guards reads, purges and filling L1
+	reads     map[string]*keyRead // keys being read from L2
+	purges    uint64
+	sub       *redis.PubSub
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ Cache = (*LayeredCache)(nil)
+
+// keyRead counts the invalidations of a key while it is read from L2, see raw
+type keyRead struct {
+	gen     uint64
+	readers int
+}
+
+// readToken is the state of an L2 read when it started
+type readToken struct {
+	read   *keyRead
+	gen    uint64
+	purges uint64
+}
+
+// invalidation is published on the channel of a LayeredCache
+type invalidation struct {
+	Origin string `json:"origin"`
+	Key    string `json:"key,omitempty"`
+	Purge  bool   `json:"purge,omitempty"`
+}
+
+type layeredOption func(*LayeredCache)
+
+// LayeredWithL1Expiration sets how long values read from redis are kept in L1, default 10s
+func LayeredWithL1Expiration(exp time.Duration) layeredOption {
+	return func(lc *LayeredCache) {
+		lc.l1Exp = exp
+	}
+}
+
+// LayeredWithChannel sets the redis channel of invalidations, default "cachemanager:invalidate:<Prefix>"
+func LayeredWithChannel(name string) layeredOption {
+	return func(lc *LayeredCache) {
+		lc.channel = name
+	}
+}
+
+// NewLayeredCache returns a LayeredCache over l1 and l2, subscribed to the invalidations of other instances.
+// Call Close to unsubscribe.
+func NewLayeredCache(l1 *CacheHelper, l2 *RedisCache, opts ...layeredOption) (*LayeredCache, error) {
+	if l1 == nil || l2 == nil || l2.cli == nil {
+		return nil, loggermanager.New(loggermanager.CodeInvalidArgument, "layered cache needs a cache and a connected redis cache")
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	lc := &LayeredCache{
+		l1:      l1,
+		l2:      l2,
+		l1Exp:   defaultL1Expiration,
+		channel: contcat("cachemanager:invalidate:", l2.Prefix),
+		origin:  hex.EncodeToString(b),
+		reads:   make(map[string]*keyRead),
+		done:    make(chan struct{}),
+	}
+	for i := range opts {
+		opts[i](lc)
+	}
+
+	lc.sub = l2.cli.Subscribe(l2.context(), lc.channel)
+	// wait for the subscription, invalidations published before it are lost
+	if _, err := lc.sub.Receive(l2.context()); err != nil {
+		lc.sub.Close()
+		return nil, loggermanager.Wrapf(err, loggermanager.CodeCacheUnavailable, "error subscribing to cache invalidations").WithDetail("channel", lc.channel)
+	}
+	go lc.listen()
+	return lc, nil
+}
+
+// Close stops listening to invalidations, L1 is no longer kept up to date
+func (lc *LayeredCache) Close() error {
+	var err error
+	lc.closeOnce.Do(func() {
+		err = lc.sub.Close()
+		<-lc.done
+	})
+	return err
+}
+
+func (lc *LayeredCache) listen() {
+	defer close(lc.done)
+	for msg := range lc.sub.Channel() {
+		lc.handle(msg.Payload)
+	}
+}
+
+// handle applies an invalidation published by another instance
+func (lc *LayeredCache) handle(payload string) {
+	var inv invalidation
+	if err := json.Unmarshal([]byte(payload), &inv); err != nil {
+		lc.l2.log().Error("invalid cache invalidation", "channel", lc.channel, loggermanager.Err(err))
+		return
+	}
+	if inv.Origin == lc.origin {
+		return
+	}
+	lc.evict(inv)
+}
+
+// evict removes the L1 copy of inv.Key, or all of them on purge, and cancels filling L1 by reads in flight
+func (lc *LayeredCache) evict(inv invalidation) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if inv.Purge {
+		lc.purges++
+		lc.l1.Purge()
+		return
+	}
+	if r := lc.reads[inv.Key]; r != nil {
+		r.gen++
+	}
+	lc.l1.Delete(inv.Key)
+}
+
+// invalidate evicts the L1 copy of key, or all of them on purge, here and in other instances
+func (lc *LayeredCache) invalidate(inv invalidation) {
+	lc.evict(inv)
+	inv.Origin = lc.origin
+	b, _ := json.Marshal(inv)
+	if err := lc.l2.cli.Publish(lc.l2.context(), lc.channel, b).Err(); err != nil {
+		lc.l2.log().Error("error publishing cache invalidation", "channel", lc.channel, "key", inv.Key, loggermanager.Err(err))
+	}
+}
+
+// raw returns the stored value of key from L1, or from L2 keeping it in L1. redis.Nil if not present.
+func (lc *LayeredCache) raw(key string) ([]byte, error) {
+	if v, ok := lc.l1.Get(key); ok {
+		if b, ok := v.([]byte); ok {
+			return b, nil
+		}
+	}
+	tok := lc.beginRead(key)
+	b, err := lc.l2.getRaw(key)
+	if err != nil {
+		b = nil
+	}
+	lc.endRead(key, tok, b)
+	return b, err
+}
+
+func (lc *LayeredCache) beginRead(key string) readToken {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	r := lc.reads[key]
+	if r == nil {
+		r = &keyRead{}
+		lc.reads[key] = r
+	}
+	r.readers++
+	return readToken{read: r, gen: r.gen, purges: lc.purges}
+}
+
+// endRead keeps b in L1 unless key was invalidated during the read, b may be older than the invalidation
+func (lc *LayeredCache) endRead(key string, tok readToken, b []byte) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if b != nil && tok.read.gen == tok.gen && lc.purges == tok.purges {
+		lc.l1.SetWithExpiration(key, b, lc.l1Exp)
+	}
+	if tok.read.readers--; tok.read.readers == 0 {
+		delete(lc.reads, key)
+	}
+}
+
+// Set stores val in redis and invalidates the L1 copies of key
+func (lc *LayeredCache) Set(key string, val interface{}) {
+	lc.l2.Set(key, val)
+	lc.invalidate(invalidation{Key: key})
+}
+
+// SetWithExpiration stores val in redis for exp and invalidates the L1 copies of key
+func (lc *LayeredCache) SetWithExpiration(key string, val interface{}, exp time.Duration) {
+	lc.l2.SetWithExpiration(key, val, exp)
+	lc.invalidate(invalidation{Key: key})
+}
+
+// SetNoExpiration stores val in redis and invalidates the L1 copies of key
+func (lc *LayeredCache) SetNoExpiration(key string, val interface{}) {
+	lc.l2.SetNoExpiration(key, val)
+	lc.invalidate(invalidation{Key: key})
+}
+
+// Get returns the value of key like RedisCache.Get, reading redis only if L1 has no copy
+func (lc *LayeredCache) Get(key string) (interface{}, bool) {
+	val, err := lc.raw(key)
+	if err == redis.Nil {
+		return nil, false
+	}
+	if err != nil {
+		lc.l2.log().Error("error getting key from redis cache", "key", key, loggermanager.Err(err))
+		return nil, false
+	}
+	_, payload, err := decodeValue(val)
+	if err != nil {
+		lc.l2.log().Error("error decompressing value from redis cache", "key", key, loggermanager.Err(err))
+		return nil, false
+	}
+	return string(payload), true
+}
+
+// GetInto decodes the value of key into v like RedisCache.GetInto, reading redis only if L1 has no copy
+func (lc *LayeredCache) GetInto(key string, v interface{}) (bool, error) {
+	val, err := lc.raw(key)
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, loggermanager.Wrapf(err, loggermanager.CodeCacheUnavailable, "error getting key from redis cache").WithDetail("key", key)
+	}
+	if err := unmarshalValue(val, v); err != nil {
+		return true, loggermanager.Wrapf(err, loggermanager.CodeCacheDecodeFailed, "error decoding cached value").WithDetail("key", key)
+	}
+	return true, nil
+}
+
+func (lc *LayeredCache) encoder() valueCodec {
+	return lc.l2.encoder()
+}
+
+// GetAll returns all keys of redis, see RedisCache.GetAll
+func (lc *LayeredCache) GetAll() map[string]interface{} {
+	return lc.l2.GetAll()
+}
+
+// Delete removes key from redis and the L1 copies
+func (lc *LayeredCache) Delete(key string) {
+	lc.l2.Delete(key)
+	lc.invalidate(invalidation{Key: key})
+}
+
+// Purge deletes the redis db and every L1 copy
+func (lc *LayeredCache) Purge() {
+	lc.l2.Purge()
+	lc.invalidate(invalidation{Purge: true})
+}
+
+// GetItemsCount returns the number of keys in redis
+func (lc *LayeredCache) GetItemsCount() int {
+	return lc.l2.GetItemsCount()
+}
+
+// SaveFile writes the keys of redis to fname, see RedisCache.SaveFile
+func (lc *LayeredCache) SaveFile(fname string) error {
+	return lc.l2.SaveFile(fname)
+}
+
+// LoadFile stores the keys of fname in redis and invalidates every L1 copy, see RedisCache.LoadFile
+func (lc *LayeredCache) LoadFile(fname string) error {
+	// some keys may be stored on error too
+	err := lc.l2.LoadFile(fname)
+	lc.invalidate(invalidation{Purge: true})
+	return err
+}
+
+// Type -
+func (lc *LayeredCache) Type() int {
+	return TypeLayeredCache
+}
@@ -0,0 +1,118 @@
+package cachemanager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLayeredCache_handle(t *testing.T) {
+	lc := &LayeredCache{l1: SetupCache(), l2: &RedisCache{}, origin: "me"}
+	lc.l1.Set("a", []byte("1"))
+	lc.l1.Set("b", []byte("2"))
+
+	lc.handle(`{"origin":"me","key":"a"}`)
+	lc.handle(`not json`)
+	if lc.l1.GetItemsCount() != 2 {
+		t.Fatal("own or invalid invalidation evicted L1 copies")
+	}
+	lc.handle(`{"origin":"other","key":"a"}`)
+	if _, ok := lc.l1.Get("a"); ok || lc.l1.GetItemsCount() != 1 {
+		t.Error("invalidation of a not applied")
+	}
+	lc.handle(`{"origin":"other","purge":true}`)
+	if lc.l1.GetItemsCount() != 0 {
+		t.Error("purge not applied")
+	}
+}
+
+func TestLayeredCache_endRead(t *testing.T) {
+	lc := &LayeredCache{l1: SetupCache(), l2: &RedisCache{}, origin: "me", l1Exp: time.Minute, reads: make(map[string]*keyRead)}
+
+	tok := lc.beginRead("a")
+	lc.handle(`{"origin":"other","key":"b"}`)
+	lc.endRead("a", tok, []byte("1"))
+	if _, ok := lc.l1.Get("a"); !ok {
+		t.Error("invalidation of another key cancelled filling L1")
+	}
+
+	tok = lc.beginRead("c")
+	lc.handle(`{"origin":"other","key":"c"}`)
+	lc.endRead("c", tok, []byte("1"))
+	tok = lc.beginRead("d")
+	lc.handle(`{"origin":"other","purge":true}`)
+	lc.endRead("d", tok, []byte("1"))
+	if lc.l1.GetItemsCount() != 0 {
+		t.Error("L1 filled with a value read before its invalidation")
+	}
+	if len(lc.reads) != 0 {
+		t.Errorf("%d reads left", len(lc.reads))
+	}
+}
+
+func TestLayeredCache_GetIntoBytes(t *testing.T) {
+	lc := &LayeredCache{l1: SetupCache(), l2: &RedisCache{}}
+	lc.l1.Set("a", []byte("value"))
+	var b []byte
+	if _, err := lc.GetInto("a", &b); err != nil {
+		t.Fatal(err)
+	}
+	b[0] = 'X'
+	if got, _ := lc.Get("a"); got != "value" {
+		t.Errorf("cached value changed to %v", got)
+	}
+}
+
+func TestLayeredCache(t *testing.T) {
+	opts := CacheOptions{Addr: "127.0.0.1:6379", Prefix: "layered", Expiration: time.Minute, L1Expiration: time.Minute}
+	c1, err := NewCache(TypeLayeredCache, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.(*LayeredCache).Close()
+	c2, err := NewCache(TypeLayeredCache, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.(*LayeredCache).Close()
+	c1.Purge()
+
+	c1.Set("config", "v1")
+	if got, ok := c2.Get("config"); !ok || got != "v1" {
+		t.Fatalf("Get(config) = %v, %v", got, ok)
+	}
+	// the invalidations of Purge and Set may still be on their way and cancel filling L1
+	waitFor(t, func() bool {
+		c2.Get("config")
+		return c2.(*LayeredCache).l1.GetItemsCount() == 1
+	})
+
+	c1.Set("config", "v2")
+	waitFor(t, func() bool { return c2.(*LayeredCache).l1.GetItemsCount() == 0 })
+	if got, _ := c2.Get("config"); got != "v2" {
+		t.Errorf("Get(config) after Set = %v, want v2", got)
+	}
+
+	c1.Delete("config")
+	waitFor(t, func() bool { return c2.(*LayeredCache).l1.GetItemsCount() == 0 })
+	if _, ok := c2.Get("config"); ok {
+		t.Error("deleted key still cached")
+	}
+
+	tc := NewTypedCache[profile](c1)
+	if err := tc.Set("p", profile{Name: "ana"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := NewTypedCache[profile](c2).Get("p"); err != nil || got.Name != "ana" {
+		t.Errorf("typed Get(p) = %+v, %v", got, err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met after invalidation")
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+}
@@ -0,0 +1,114 @@
+package cachemanager
+
+import (
+	"reflect"
+)
+
+// Sizer reports the bytes held by a cached value, for values whose estimate would be off,
+// e.g. types holding memory outside of Go or sharing large buffers.
+type Sizer interface {
+	Size() int64
+}
+
+// entryOverhead approximates the bookkeeping of go-cache and the evictor per item
+const entryOverhead = 96
+
+// itemSize estimates the bytes held by the item of key
+func itemSize(key string, val interface{}) int64 {
+	return entryOverhead + int64(len(key)) + sizeOf(val)
+}
+
+// sizeOf returns val.Size() for a Sizer, otherwise walks val adding up the memory it references.
+// Memory shared between values is counted for each of them.
+func sizeOf(val interface{}) int64 {
+	switch v := val.(type) {
+	case nil:
+		return 0
+	case Sizer:
+		return v.Size()
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(cap(v))
+	}
+	rv := reflect.ValueOf(val)
+	return int64(rv.Type().Size()) + indirectSize(rv, make(map[uintptr]bool))
+}
+
+// indirectSize returns the bytes referenced by v outside of its own memory
+func indirectSize(v reflect.Value, seen map[uintptr]bool) int64 {
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Ptr:
+		if v.IsNil() || seen[v.Pointer()] {
+			return 0
+		}
+		seen[v.Pointer()] = true
+		return int64(v.Type().Elem().Size()) + indirectSize(v.Elem(), seen)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return int64(v.Elem().Type().Size()) + indirectSize(v.Elem(), seen)
+	case reflect.Slice:
+		if v.IsNil() || seen[v.Pointer()] {
+			return 0
+		}
+		seen[v.Pointer()] = true
+		n := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		if hasIndirect(v.Type().Elem()) {
+			for i := 0; i < v.Len(); i++ {
+				n += indirectSize(v.Index(i), seen)
+			}
+		}
+		return n
+	case reflect.Array:
+		var n int64
+		if hasIndirect(v.Type().Elem()) {
+			for i := 0; i < v.Len(); i++ {
+				n += indirectSize(v.Index(i), seen)
+			}
+		}
+		return n
+	case reflect.Map:
+		if v.IsNil() || seen[v.Pointer()] {
+			return 0
+		}
+		seen[v.Pointer()] = true
+		t := v.Type()
+		n := int64(v.Len()) * int64(t.Key().Size()+t.Elem().Size())
+		if hasIndirect(t.Key()) || hasIndirect(t.Elem()) {
+			iter := v.MapRange()
+			for iter.Next() {
+				n += indirectSize(iter.Key(), seen) + indirectSize(iter.Value(), seen)
+			}
+		}
+		return n
+	case reflect.Struct:
+		var n int64
+		for i := 0; i < v.NumField(); i++ {
+			n += indirectSize(v.Field(i), seen)
+		}
+		return n
+	}
+	// numbers, bools, channels and functions are counted by their own size
+	return 0
+}
+
+// hasIndirect reports whether values of t may reference memory outside of themselves
+func hasIndirect(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return true
+	case reflect.Array:
+		return hasIndirect(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasIndirect(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package cachemanager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+type fixedSize struct{}
+
+func (fixedSize) Size() int64 { return 1 << 20 }
+
+type node struct {
+	Name string
+	Next *node
+}
+
+func TestSizeOf(t *testing.T) {
+	blob := `{"name":"` + strings.Repeat("x", 4096) + `"}`
+	loop := &node{Name: "a"}
+	loop.Next = loop
+
+	tests := []struct {
+		name     string
+		val      interface{}
+		min, max int64
+	}{
+		{"nil", nil, 0, 0},
+		{"string", "abcd", 4, 4},
+		{"bytes", make([]byte, 10, 32), 32, 32},
+		{"int", 42, 8, 8},
+		{"sizer", fixedSize{}, 1 << 20, 1 << 20},
+		{"gjson", gjson.Parse(blob), 4096, 4096 + 256},
+		{"map", map[string]string{"k": strings.Repeat("v", 1000)}, 1000, 1100},
+		{"slice of strings", []string{strings.Repeat("a", 100), strings.Repeat("b", 100)}, 200, 300},
+		{"cycle", loop, 1, 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sizeOf(tt.val); got < tt.min || got > tt.max {
+				t.Errorf("sizeOf() = %d, want %d..%d", got, tt.min, tt.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package cachemanager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/crearosoft/corelib/loggermanager"
+)
+
+// TypedCache stores and returns values of type T in any Cache, so code behaves the same for every backend.
+// Caches storing bytes, such as RedisCache, hold values encoded by the codec, in memory caches hold T itself.
+// RedisCache values are decoded with the codec they were stored with.
+type TypedCache[T any] struct {
+	cache Cache
+	codec Codec
+}
+
+type typedOption func(*typedConfig)
+
+type typedConfig struct {
+	codec Codec
+}
+
+// WithCodec sets the codec of a TypedCache, default the codec of RedisCache or JSONCodec
+func WithCodec(c Codec) typedOption {
+	return func(cfg *typedConfig) {
+		cfg.codec = c
+	}
+}
+
+// NewTypedCache returns a TypedCache storing in c
+func NewTypedCache[T any](c Cache, opts ...typedOption) *TypedCache[T] {
+	cfg := typedConfig{}
+	for i := range opts {
+		opts[i](&cfg)
+	}
+	return &TypedCache[T]{cache: c, codec: cfg.codec}
+}
+
+// Cache returns the underlying cache
+func (tc *TypedCache[T]) Cache() Cache {
+	return tc.cache
+}
+
+// Set stores val with the default expiration of the cache
+func (tc *TypedCache[T]) Set(key string, val T) error {
+	v, err := tc.encode(key, val)
+	if err != nil {
+		return err
+	}
+	tc.cache.Set(key, v)
+	return nil
+}
+
+// SetWithExpiration stores val for exp
+func (tc *TypedCache[T]) SetWithExpiration(key string, val T, exp time.Duration) error {
+	v, err := tc.encode(key, val)
+	if err != nil {
+		return err
+	}
+	tc.cache.SetWithExpiration(key, v, exp)
+	return nil
+}
+
+// SetNoExpiration stores val until it is deleted
+func (tc *TypedCache[T]) SetNoExpiration(key string, val T) error {
+	v, err := tc.encode(key, val)
+	if err != nil {
+		return err
+	}
+	tc.cache.SetNoExpiration(key, v)
+	return nil
+}
+
+// Get returns the value of key. The error is loggermanager.ErrCacheMiss if key is not cached
+// and has the code CACHE_DECODE_FAILED if the value is not a T.
+func (tc *TypedCache[T]) Get(key string) (T, error) {
+	var zero T
+	if hc, ok := tc.cache.(headerCache); ok {
+		var t T
+		found, err := hc.GetInto(key, &t)
+		if !found && err == nil {
+			return zero, loggermanager.New(loggermanager.CodeCacheMiss, "key not found in cache").WithDetail("key", key)
+		}
+		if err != nil {
+			return zero, err
+		}
+		return t, nil
+	}
+	v, ok := tc.cache.Get(key)
+	if !ok {
+		return zero, loggermanager.New(loggermanager.CodeCacheMiss, "key not found in cache").WithDetail("key", key)
+	}
+	if !storesBytes(tc.cache) {
+		if t, ok := v.(T); ok {
+			return t, nil
+		}
+		return zero, loggermanager.Newf(loggermanager.CodeCacheDecodeFailed, "cached value is a %T, not a %T", v, zero).WithDetail("key", key)
+	}
+	var data []byte
+	switch d := v.(type) {
+	case string:
+		data = []byte(d)
+	case []byte:
+		data = d
+	default:
+		return zero, loggermanager.Newf(loggermanager.CodeCacheDecodeFailed, "cached value is a %T, not encoded", v).WithDetail("key", key)
+	}
+	var t T
+	if err := tc.codecOrJSON().Unmarshal(data, &t); err != nil {
+		return zero, loggermanager.Wrapf(err, loggermanager.CodeCacheDecodeFailed, "error decoding cached value").WithDetail("key", key)
+	}
+	return t, nil
+}
+
+// Delete removes key
+func (tc *TypedCache[T]) Delete(key string) {
+	tc.cache.Delete(key)
+}
+
+func (tc *TypedCache[T]) encode(key string, val T) (interface{}, error) {
+	if !storesBytes(tc.cache) {
+		return val, nil
+	}
+	var b []byte
+	var err error
+	hc, header := tc.cache.(headerCache)
+	if header {
+		// encoded here rather than by Set, which only logs errors
+		vc := hc.encoder()
+		if tc.codec != nil {
+			vc.codec = tc.codec
+		}
+		b, err = vc.encode(val)
+	} else {
+		b, err = tc.codecOrJSON().Marshal(val)
+	}
+	if err != nil {
+		return nil, loggermanager.Wrapf(err, loggermanager.CodeCacheMarshalFailed, "error encoding value").WithDetail("key", key).WithDetail("type", fmt.Sprintf("%T", val))
+	}
+	if header {
+		return encodedValue(b), nil
+	}
+	return b, nil
+}
+
+func (tc *TypedCache[T]) codecOrJSON() Codec {
+	if tc.codec == nil {
+		return JSONCodec{}
+	}
+	return tc.codec
+}
+
+// headerCache is implemented by caches storing values with a header naming their codec
+type headerCache interface {
+	encoder() valueCodec
+	GetInto(key string, v interface{}) (bool, error)
+}
+
+// storesBytes reports whether c keeps encoded values rather than Go objects
+func storesBytes(c Cache) bool {
+	return c.Type() == TypeRedisCache || c.Type() == TypeLayeredCache
+}
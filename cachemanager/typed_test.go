@@ -0,0 +1,65 @@
+package cachemanager
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/crearosoft/corelib/loggermanager"
+)
+
+type profile struct {
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+}
+
+// byteCache stores what it is given as a string, like RedisCache
+type byteCache struct {
+	*CacheHelper
+}
+
+func (c byteCache) Set(key string, val interface{}) {
+	if b, ok := val.([]byte); ok {
+		val = string(b)
+	}
+	c.CacheHelper.Set(key, val)
+}
+
+func (c byteCache) Type() int {
+	return TypeRedisCache
+}
+
+func TestTypedCache(t *testing.T) {
+	backends := map[string]Cache{
+		"memory": SetupCache(),
+		"bytes":  byteCache{SetupCache()},
+	}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			tc := NewTypedCache[profile](backend)
+			want := profile{Name: "ana", Roles: []string{"admin"}}
+			if err := tc.Set("p1", want); err != nil {
+				t.Fatal(err)
+			}
+			got, err := tc.Get("p1")
+			if err != nil || got.Name != want.Name || len(got.Roles) != 1 || got.Roles[0] != "admin" {
+				t.Errorf("Get(p1) = %+v, %v", got, err)
+			}
+
+			if _, err := tc.Get("missing"); !errors.Is(err, loggermanager.ErrCacheMiss) {
+				t.Errorf("Get(missing) error = %v", err)
+			}
+
+			backend.Set("bad", "not a profile")
+			if _, err := tc.Get("bad"); !errors.Is(err, loggermanager.ErrCacheDecodeFailed) {
+				t.Errorf("Get(bad) error = %v", err)
+			}
+		})
+	}
+}
+
+func TestTypedCacheEncodeError(t *testing.T) {
+	tc := NewTypedCache[func()](byteCache{SetupCache()})
+	if err := tc.Set("f", func() {}); !errors.Is(err, loggermanager.ErrCacheMarshalFailed) {
+		t.Errorf("Set(func) error = %v", err)
+	}
+}
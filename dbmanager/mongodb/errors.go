@@ -0,0 +1,27 @@
+package mongodb
+
+import (
+	"errors"
+
+	"github.com/crearosoft/corelib/loggermanager"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func init() {
+	loggermanager.RegisterErrorMapper(classify)
+}
+
+// classify maps mongo driver errors to catalog codes for problem responses
+func classify(err error) *loggermanager.CoreError {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return loggermanager.Wrapf(err, loggermanager.CodeDocumentNotFound, "document not found")
+	case mongo.IsDuplicateKeyError(err):
+		return loggermanager.Wrapf(err, loggermanager.CodeDuplicateKey, "duplicate key")
+	case mongo.IsTimeout(err):
+		return loggermanager.Wrapf(err, loggermanager.CodeDatabaseTimeout, "database operation timed out")
+	case mongo.IsNetworkError(err), errors.Is(err, mongo.ErrClientDisconnected):
+		return loggermanager.Wrapf(err, loggermanager.CodeDatabaseUnavailable, "database unreachable")
+	}
+	return nil
+}
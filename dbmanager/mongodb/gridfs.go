@@ -3,11 +3,11 @@ package mongodb
 import (
 	"bytes"
 	"context"
-	"errors"
 	"io"
 	"strings"
 	"time"
 
+	"github.com/crearosoft/corelib/loggermanager"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/gridfs"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -21,11 +21,11 @@ func SaveFileToGridFS(db *mongo.Database, bucketName, fileName string, source io
 
 	//Validations
 	if db == nil {
-		return "", "", errors.New("db Required")
+		return "", "", loggermanager.New(loggermanager.CodeInvalidArgument, "db Required")
 	} else if bucketName == "" {
-		return "", "", errors.New("bucketName required")
+		return "", "", loggermanager.New(loggermanager.CodeInvalidArgument, "bucketName required")
 	} else if source == nil {
-		return "", "", errors.New("invalid source")
+		return "", "", loggermanager.New(loggermanager.CodeInvalidArgument, "invalid source")
 	}
 
 	//Set bucket config
@@ -56,11 +56,11 @@ func GetFileFromGridFS(db *mongo.Database, bucketName, fileName string) ([]byte,
 
 	//Validations
 	if db == nil {
-		return nil, errors.New("db Required")
+		return nil, loggermanager.New(loggermanager.CodeInvalidArgument, "db Required")
 	} else if bucketName == "" {
-		return nil, errors.New("bucketName required")
+		return nil, loggermanager.New(loggermanager.CodeInvalidArgument, "bucketName required")
 	} else if fileName == "" {
-		return nil, errors.New("fileName required'")
+		return nil, loggermanager.New(loggermanager.CodeInvalidArgument, "fileName required")
 	}
 
 	//Set bucket config
@@ -96,16 +96,16 @@ func GetDBInstance(serverIPAddress, port, dbName string, timeOutInSeconds int) (
 
 	//Validations
 	if serverIPAddress == "" {
-		return nil, errors.New("serverIPAddress required")
+		return nil, loggermanager.New(loggermanager.CodeInvalidArgument, "serverIPAddress required")
 	} else if dbName == "" {
-		return nil, errors.New("dbName required")
+		return nil, loggermanager.New(loggermanager.CodeInvalidArgument, "dbName required")
 	} else if timeOutInSeconds <= 0 {
-		return nil, errors.New("valid timeOutInSeconds required")
+		return nil, loggermanager.New(loggermanager.CodeInvalidArgument, "valid timeOutInSeconds required")
 	}
 
 	ipElements := strings.Split(serverIPAddress, ".")
 	if len(ipElements) != 4 {
-		return nil, errors.New("invalid serverIPAddress")
+		return nil, loggermanager.New(loggermanager.CodeInvalidArgument, "invalid serverIPAddress")
 	}
 
 	if port == "" {
@@ -0,0 +1,393 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/crearosoft/corelib/loggermanager"
+)
+
+const (
+	defaultLogCollection    = "logs"
+	defaultLogSizeMB        = 100
+	defaultLogBatchSize     = 100
+	defaultLogFlushInterval = 2 * time.Second
+	defaultLogQueueSize     = 10000
+	defaultLogQueryLimit    = 100
+
+	// errors of the sink are logged with this name and never written to the sink itself
+	logSinkLoggerName = "mongodb.logsink"
+
+	codeNamespaceExists = 48
+)
+
+// LogSinkOptions configures a capped collection receiving the entries of loggermanager
+type LogSinkOptions struct {
+	HostName   string // host of InitUsingJSON, empty uses the default host
+	Collection string // default "logs"
+
+	SizeMB  int64 // size of the capped collection, default 100
+	MaxDocs int64 // optional document limit of the capped collection
+
+	Level         string        // entries at this level and above are written, default info
+	BatchSize     int           // queued entries which trigger an insert, default 100
+	FlushInterval time.Duration // default 2s
+	QueueSize     int           // entries beyond it are dropped, default 10000
+}
+
+// LogEntry is the document written for every log entry.
+// Severity holds the numeric level so queries can select a level and above.
+type LogEntry struct {
+	ID       primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	Time     time.Time              `bson:"ts" json:"ts"`
+	Level    string                 `bson:"level" json:"level"`
+	Severity int                    `bson:"severity" json:"severity"`
+	Logger   string                 `bson:"logger,omitempty" json:"logger,omitempty"`
+	Caller   string                 `bson:"caller,omitempty" json:"caller,omitempty"`
+	Message  string                 `bson:"msg" json:"msg"`
+	Fields   map[string]interface{} `bson:"fields,omitempty" json:"fields,omitempty"`
+}
+
+// LogQuery selects log entries, zero values match everything
+type LogQuery struct {
+	Level   string // minimum level, e.g. "error"
+	Since   time.Time
+	Until   time.Time
+	Logger  string // name, children included
+	Message string // substring of the message
+	Limit   int64  // newest entries returned, default 100
+}
+
+// LogSink writes log entries into a capped collection in batches
+type LogSink struct {
+	opts       LogSinkOptions
+	collection *mongo.Collection
+	minLevel   zapcore.Level
+
+	mu      sync.Mutex
+	queue   []interface{}
+	dropped uint64
+
+	insertMu   sync.Mutex
+	removeCore func()
+	kick       chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
+	wg         sync.WaitGroup
+}
+
+// NewLogSink creates the capped collection and its indexes when missing. An existing collection
+// must be capped. See InstallLogSink to feed it with the package logger.
+func NewLogSink(ctx context.Context, opts LogSinkOptions) (*LogSink, error) {
+	if opts.Collection == "" {
+		opts.Collection = defaultLogCollection
+	}
+	if opts.SizeMB <= 0 {
+		opts.SizeMB = defaultLogSizeMB
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultLogBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaultLogFlushInterval
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultLogQueueSize
+	}
+	minLevel := zapcore.InfoLevel
+	if opts.Level != "" {
+		if err := minLevel.UnmarshalText([]byte(opts.Level)); err != nil {
+			return nil, loggermanager.Wrapf(err, loggermanager.CodeInvalidConfig, "invalid log sink level %q", opts.Level)
+		}
+	}
+
+	db, err := database(ctx, opts.HostName)
+	if err != nil {
+		return nil, err
+	}
+	create := options.CreateCollection().SetCapped(true).SetSizeInBytes(opts.SizeMB << 20)
+	if opts.MaxDocs > 0 {
+		create.SetMaxDocuments(opts.MaxDocs)
+	}
+	var cmdErr mongo.CommandError
+	if err := db.CreateCollection(ctx, opts.Collection, create); err != nil {
+		if !errors.As(err, &cmdErr) || cmdErr.Code != codeNamespaceExists {
+			return nil, err
+		}
+		// a plain collection would grow without bound
+		specs, err := db.ListCollectionSpecifications(ctx, bson.D{{Key: "name", Value: opts.Collection}})
+		if err != nil {
+			return nil, err
+		}
+		if len(specs) != 1 || !capped(specs[0].Options) {
+			return nil, loggermanager.New(loggermanager.CodeInvalidConfig, "log sink collection exists and is not capped").WithDetail("collection", opts.Collection)
+		}
+	}
+	collection := db.Collection(opts.Collection)
+	_, err = collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "severity", Value: 1}, {Key: "ts", Value: -1}}},
+		{Keys: bson.D{{Key: "ts", Value: -1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &LogSink{
+		opts:       opts,
+		collection: collection,
+		minLevel:   minLevel,
+		kick:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+// InstallLogSink creates a sink and feeds it every entry of the package logger. Close uninstalls it.
+func InstallLogSink(ctx context.Context, opts LogSinkOptions) (*LogSink, error) {
+	s, err := NewLogSink(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.removeCore = loggermanager.AddCore(&logSinkCore{s: s})
+	return s, nil
+}
+
+// Flush inserts the queued entries now
+func (s *LogSink) Flush(ctx context.Context) error {
+	s.insertMu.Lock()
+	defer s.insertMu.Unlock()
+	s.mu.Lock()
+	queue := s.queue
+	s.queue = nil
+	s.mu.Unlock()
+	if len(queue) == 0 {
+		return nil
+	}
+	_, err := s.collection.InsertMany(ctx, queue, options.InsertMany().SetOrdered(false))
+	if err != nil {
+		atomic.AddUint64(&s.dropped, uint64(len(queue)))
+		logger.Named("logsink").Warn("failed to write log entries", "collection", s.opts.Collection, "entries", len(queue), loggermanager.Err(err))
+	}
+	return err
+}
+
+// Dropped returns the number of entries lost because the queue was full or an insert failed
+func (s *LogSink) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close uninstalls the sink and inserts the queued entries
+func (s *LogSink) Close() error {
+	s.closeOnce.Do(func() {
+		if s.removeCore != nil {
+			s.removeCore()
+		}
+		close(s.done)
+	})
+	s.wg.Wait()
+	return s.Flush(context.Background())
+}
+
+// Find returns the newest entries of the sink matching q, newest first
+func (s *LogSink) Find(ctx context.Context, q LogQuery) ([]LogEntry, error) {
+	return findLogs(ctx, s.collection, q)
+}
+
+// RecentErrors returns the newest entries at error level and above, newest first
+func (s *LogSink) RecentErrors(ctx context.Context, limit int64) ([]LogEntry, error) {
+	return s.Find(ctx, LogQuery{Level: "error", Limit: limit})
+}
+
+// FindLogs queries a log collection written by a LogSink, possibly of another process
+func FindLogs(ctx context.Context, hostName, collection string, q LogQuery) ([]LogEntry, error) {
+	db, err := database(ctx, hostName)
+	if err != nil {
+		return nil, err
+	}
+	if collection == "" {
+		collection = defaultLogCollection
+	}
+	return findLogs(ctx, db.Collection(collection), q)
+}
+
+func findLogs(ctx context.Context, collection *mongo.Collection, q LogQuery) ([]LogEntry, error) {
+	filter, err := q.filter()
+	if err != nil {
+		return nil, err
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultLogQueryLimit
+	}
+	cur, err := collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "ts", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	entries := []LogEntry{}
+	if err := cur.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// filter builds the selector of q
+func (q LogQuery) filter() (bson.D, error) {
+	filter := bson.D{}
+	if q.Level != "" {
+		var lvl zapcore.Level
+		if err := lvl.UnmarshalText([]byte(q.Level)); err != nil {
+			return nil, loggermanager.Wrapf(err, loggermanager.CodeInvalidArgument, "invalid level %q", q.Level)
+		}
+		filter = append(filter, bson.E{Key: "severity", Value: bson.M{"$gte": int(lvl)}})
+	}
+	if !q.Since.IsZero() || !q.Until.IsZero() {
+		ts := bson.M{}
+		if !q.Since.IsZero() {
+			ts["$gte"] = q.Since
+		}
+		if !q.Until.IsZero() {
+			ts["$lte"] = q.Until
+		}
+		filter = append(filter, bson.E{Key: "ts", Value: ts})
+	}
+	if q.Logger != "" {
+		filter = append(filter, bson.E{Key: "logger", Value: bson.M{"$regex": "^" + regexp.QuoteMeta(q.Logger) + `(\.|$)`}})
+	}
+	if q.Message != "" {
+		filter = append(filter, bson.E{Key: "msg", Value: bson.M{"$regex": regexp.QuoteMeta(q.Message)}})
+	}
+	return filter, nil
+}
+
+func (s *LogSink) run() {
+	defer s.wg.Done()
+	t := time.NewTicker(s.opts.FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.kick:
+		case <-t.C:
+		case <-s.done:
+			return
+		}
+		s.Flush(context.Background())
+	}
+}
+
+func (s *LogSink) enqueue(e *LogEntry) {
+	s.mu.Lock()
+	if len(s.queue) >= s.opts.QueueSize {
+		s.mu.Unlock()
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+	s.queue = append(s.queue, e)
+	full := len(s.queue) >= s.opts.BatchSize
+	s.mu.Unlock()
+	if full {
+		select {
+		case s.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// capped reports whether the options of a collection specification make it a capped collection
+func capped(opts bson.Raw) bool {
+	v, err := opts.LookupErr("capped")
+	return err == nil && v.Type == bson.TypeBoolean && v.Boolean()
+}
+
+// database returns the database configured for hostName
+func database(ctx context.Context, hostName string) (*mongo.Database, error) {
+	client, err := getMongoConnection(ctx, hostName)
+	if err != nil {
+		return nil, err
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if hostName == "" {
+		hostName = defaultHost
+	}
+	host, ok := config[hostName]
+	if !ok {
+		return nil, errNoConfiguration(hostName)
+	}
+	return client.Database(host.Database), nil
+}
+
+// newLogEntry converts a zap entry into its document
+func newLogEntry(ent zapcore.Entry, fields []zapcore.Field) *LogEntry {
+	e := &LogEntry{
+		Time:     ent.Time,
+		Level:    ent.Level.String(),
+		Severity: int(ent.Level),
+		Logger:   ent.LoggerName,
+		Message:  ent.Message,
+	}
+	if ent.Caller.Defined {
+		e.Caller = ent.Caller.TrimmedPath()
+	}
+	if len(fields) > 0 {
+		enc := zapcore.NewMapObjectEncoder()
+		for _, f := range fields {
+			f.AddTo(enc)
+		}
+		delete(enc.Fields, "errorVerbose")
+		e.Fields = enc.Fields
+	}
+	return e
+}
+
+// logSinkCore queues the entries of the package logger for a LogSink
+type logSinkCore struct {
+	s      *LogSink
+	fields []zapcore.Field
+}
+
+func (c *logSinkCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= c.s.minLevel
+}
+
+func (c *logSinkCore) With(fields []zapcore.Field) zapcore.Core {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	return &logSinkCore{s: c.s, fields: append(append(all, c.fields...), fields...)}
+}
+
+func (c *logSinkCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) && ent.LoggerName != logSinkLoggerName {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *logSinkCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.LoggerName == logSinkLoggerName {
+		// failures of the sink would be queued for the sink again
+		return nil
+	}
+	c.s.enqueue(newLogEntry(ent, append(c.fields[:len(c.fields):len(c.fields)], fields...)))
+	return nil
+}
+
+// Sync starts inserting the queued entries without waiting, every Sync of the package logger
+// would wait for the database otherwise. Close inserts what is left.
+func (c *logSinkCore) Sync() error {
+	select {
+	case c.s.kick <- struct{}{}:
+	default:
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package mongodb
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewLogEntry(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	ent := zapcore.Entry{
+		Level:      zapcore.ErrorLevel,
+		Time:       ts,
+		LoggerName: "cachemanager",
+		Message:    "failed",
+		Caller:     zapcore.NewEntryCaller(0, "/src/corelib/cachemanager/cache_redis.go", 42, true),
+	}
+	e := newLogEntry(ent, []zapcore.Field{zap.String("key", "k1"), zap.Error(errors.New("boom"))})
+
+	if e.Level != "error" || e.Severity != int(zapcore.ErrorLevel) || e.Caller != "cachemanager/cache_redis.go:42" {
+		t.Errorf("entry = %+v", e)
+	}
+	if e.Fields["key"] != "k1" || e.Fields["error"] != "boom" || len(e.Fields) != 2 {
+		t.Errorf("fields = %v", e.Fields)
+	}
+}
+
+func TestLogSinkCoreSkipsOwnEntries(t *testing.T) {
+	s := &LogSink{opts: LogSinkOptions{QueueSize: 10, BatchSize: 10}, minLevel: zapcore.WarnLevel, kick: make(chan struct{}, 1)}
+	l := zap.New(&logSinkCore{s: s})
+
+	l.Info("below level")
+	l.Named("mongodb").Named("logsink").Warn("failed to write log entries")
+	l.Named("mongodb").With(zap.String("host", "h1")).Warn("kept")
+
+	if len(s.queue) != 1 {
+		t.Fatalf("queued %d entries, want 1", len(s.queue))
+	}
+	if e := s.queue[0].(*LogEntry); e.Message != "kept" || e.Fields["host"] != "h1" {
+		t.Errorf("queued %+v", e)
+	}
+}
+
+func TestLogQueryFilter(t *testing.T) {
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	got, err := LogQuery{Level: "warn", Since: since, Logger: "mongodb"}.filter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bson.D{
+		{Key: "severity", Value: bson.M{"$gte": int(zapcore.WarnLevel)}},
+		{Key: "ts", Value: bson.M{"$gte": since}},
+		{Key: "logger", Value: bson.M{"$regex": `^mongodb(\.|$)`}},
+	}
+	gotRaw, _ := bson.Marshal(got)
+	wantRaw, _ := bson.Marshal(want)
+	if !bytes.Equal(gotRaw, wantRaw) {
+		t.Errorf("filter = %v, want %v", got, want)
+	}
+
+	if _, err := (LogQuery{Level: "loud"}).filter(); err == nil {
+		t.Error("invalid level accepted")
+	}
+}
+
+func TestCapped(t *testing.T) {
+	for _, tt := range []struct {
+		opts bson.D
+		want bool
+	}{
+		{bson.D{{Key: "capped", Value: true}, {Key: "size", Value: 1 << 20}}, true},
+		{bson.D{{Key: "capped", Value: false}}, false},
+		{bson.D{}, false},
+	} {
+		raw, _ := bson.Marshal(tt.opts)
+		if got := capped(raw); got != tt.want {
+			t.Errorf("capped(%v) = %v", tt.opts, got)
+		}
+	}
+}
+
+func TestLogSinkCoreSyncDoesNotInsert(t *testing.T) {
+	// no collection, an insert would panic
+	s := &LogSink{opts: LogSinkOptions{QueueSize: 10, BatchSize: 10}, kick: make(chan struct{}, 1)}
+	c := &logSinkCore{s: s}
+	c.Write(zapcore.Entry{Level: zapcore.ErrorLevel, Message: "queued"}, nil)
+	if err := c.Sync(); err != nil || len(s.queue) != 1 {
+		t.Fatalf("Sync = %v, %d queued", err, len(s.queue))
+	}
+	select {
+	case <-s.kick:
+	default:
+		t.Error("Sync did not start a flush")
+	}
+}
@@ -35,6 +35,14 @@ type MongoHost struct {
 	IsDisabled      bool          `json:"isDisabled" `
 }
 
+// Redacted hides the password when the host is logged
+func (host MongoHost) Redacted() interface{} {
+	if host.Password != "" {
+		host.Password = loggermanager.RedactedValue
+	}
+	return host
+}
+
 var instances map[string]*mongo.Client
 var mutex sync.Mutex
 var once sync.Once
@@ -43,6 +51,8 @@ var config map[string]MongoHost
 
 var defaultHost string
 
+var logger = loggermanager.Named("mongodb")
+
 func init() {
 	config = make(map[string]MongoHost)
 }
@@ -75,19 +85,19 @@ func InitUsingJSON(configs []MongoHost) error {
 			client, err := mongo.NewClient(clientOption)
 			if err != nil {
 				sessionError = err
-				loggermanager.LogError(sessionError)
+				logger.Error("failed to create mongo client", "host", hostDetails.HostName, loggermanager.Err(sessionError))
 				return
 			}
 			err = client.Connect(context.Background())
 			if err != nil {
 				sessionError = err
-				loggermanager.LogError(sessionError)
+				logger.Error("failed to connect to mongo", "host", hostDetails.HostName, loggermanager.Err(sessionError))
 				return
 			}
 			err = client.Ping(context.Background(), readpref.Primary())
 			if err != nil {
 				sessionError = err
-				loggermanager.LogError("failed to connect to primary - ", sessionError)
+				logger.Error("failed to connect to primary", "host", hostDetails.HostName, loggermanager.Err(sessionError))
 				return
 			}
 			instances[hostDetails.HostName] = client
@@ -105,7 +115,7 @@ func DeleteSession(hostName string) error {
 	defer mutex.Unlock()
 	mutex.Lock()
 	if _, ok := instances[hostName]; !ok {
-		return loggermanager.Wrap("NO_HOST_FOUND")
+		return loggermanager.New(loggermanager.CodeNoHostFound, "NO_HOST_FOUND").WithDetail("host", hostName)
 	}
 	delete(instances, hostName)
 	return nil
@@ -119,7 +129,7 @@ func InitNewSession(hostDetails MongoHost) error {
 		instances = make(map[string]*mongo.Client)
 	}
 	if _, ok := instances[hostDetails.HostName]; ok {
-		return loggermanager.Wrap("DUPLICATE_HOSTNAME")
+		return loggermanager.New(loggermanager.CodeDuplicateHostname, "DUPLICATE_HOSTNAME").WithDetail("host", hostDetails.HostName)
 	}
 	clientOption := options.Client()
 	clientOption.SetHosts([]string{bindMongoServerWithPort(hostDetails.Server, hostDetails.Port)}).
@@ -136,12 +146,12 @@ func InitNewSession(hostDetails MongoHost) error {
 	}
 	client, err := mongo.NewClient(clientOption)
 	if err != nil {
-		loggermanager.LogError(err)
+		logger.Error("failed to create mongo client", "host", hostDetails.HostName, loggermanager.Err(err))
 		return err
 	}
 	err = client.Connect(context.Background())
 	if err != nil {
-		loggermanager.LogError(err)
+		logger.Error("failed to connect to mongo", "host", hostDetails.HostName, loggermanager.Err(err))
 		return err
 	}
 	instances[hostDetails.HostName] = client
@@ -150,36 +160,42 @@ func InitNewSession(hostDetails MongoHost) error {
 
 //GetMongoConnection method
 func GetMongoConnection(hostName string) (*mongo.Client, error) {
+	return getMongoConnection(context.Background(), hostName)
+}
+
+func getMongoConnection(ctx context.Context, hostName string) (*mongo.Client, error) {
 	mutex.Lock()
 	defer mutex.Unlock()
 	if instances == nil {
-		return nil, loggermanager.Wrap("MONGO_INIT_NOT_DONE")
+		return nil, loggermanager.New(loggermanager.CodeMongoInitNotDone, "MONGO_INIT_NOT_DONE")
 	}
 	if hostName == "" {
 		if instance, ok := instances[defaultHost]; ok {
-			err := instance.Ping(context.Background(), readpref.Primary())
+			err := instance.Ping(ctx, readpref.Primary())
 			if err != nil {
-				loggermanager.LogError(err)
+				loggermanager.ContextLogger(ctx, "mongodb").Error("failed to ping primary", "host", defaultHost, loggermanager.Err(err))
 				return nil, err
 			}
 			return instance, nil
 		}
 	}
 	if instance, ok := instances[hostName]; ok {
-		err := instance.Ping(context.Background(), readpref.Primary())
+		err := instance.Ping(ctx, readpref.Primary())
 		if err != nil {
-			loggermanager.LogError(err)
+			loggermanager.ContextLogger(ctx, "mongodb").Error("failed to ping primary", "host", hostName, loggermanager.Err(err))
 			return nil, err
 		}
 		return instance, nil
 	}
-	return nil, loggermanager.Wrap("Session not found for instance: " + hostName)
+	return nil, loggermanager.New(loggermanager.CodeSessionNotFound, "Session not found for instance: "+hostName).WithDetail("host", hostName)
 }
 
 // MongoDAO mongo DAO struct
 type MongoDAO struct {
 	hostName       string
 	collectionName string
+	ctx            context.Context
+	ctxLog         loggermanager.Logger // logger of ctx, see WithContext
 }
 
 // GetMongoDAOWithHost return mongo DAO instance
@@ -197,9 +213,31 @@ func GetMongoDAO(collection string) *MongoDAO {
 	}
 }
 
+// WithContext returns a copy of the DAO which uses ctx for queries and logs with the correlation values of ctx
+func (mg *MongoDAO) WithContext(ctx context.Context) *MongoDAO {
+	dao := *mg
+	dao.ctx = ctx
+	dao.ctxLog = loggermanager.ContextLogger(ctx, "mongodb")
+	return &dao
+}
+
+func (mg *MongoDAO) context() context.Context {
+	if mg.ctx != nil {
+		return mg.ctx
+	}
+	return context.Background()
+}
+
+func (mg *MongoDAO) log() loggermanager.Logger {
+	if mg.ctxLog != nil {
+		return mg.ctxLog
+	}
+	return logger
+}
+
 // SaveData Save data in mongo db
 func (mg *MongoDAO) SaveData(data interface{}) (string, error) {
-	session, sessionError := GetMongoConnection(mg.hostName)
+	session, sessionError := getMongoConnection(mg.context(), mg.hostName)
 	if sessionError != nil {
 		return "", sessionError
 	}
@@ -209,10 +247,10 @@ func (mg *MongoDAO) SaveData(data interface{}) (string, error) {
 	}
 	db, ok := config[mg.hostName]
 	if !ok {
-		return "", loggermanager.Wrap("No_Configuration_Found_For_Host: " + mg.hostName)
+		return "", errNoConfiguration(mg.hostName)
 	}
 	collection := session.Database(db.Database).Collection(mg.collectionName)
-	opts, insertError := collection.InsertOne(context.Background(), data)
+	opts, insertError := collection.InsertOne(mg.context(), data)
 	if insertError != nil {
 		return "", insertError
 	}
@@ -221,7 +259,7 @@ func (mg *MongoDAO) SaveData(data interface{}) (string, error) {
 
 // UpdateAll update all
 func (mg *MongoDAO) UpdateAll(selector map[string]interface{}, data interface{}) error {
-	session, sessionError := GetMongoConnection(mg.hostName)
+	session, sessionError := getMongoConnection(mg.context(), mg.hostName)
 	if sessionError != nil {
 		return sessionError
 	}
@@ -231,11 +269,11 @@ func (mg *MongoDAO) UpdateAll(selector map[string]interface{}, data interface{})
 	}
 	db, ok := config[mg.hostName]
 	if !ok {
-		return loggermanager.Wrap("No_Configuration_Found_For_Host: " + mg.hostName)
+		return errNoConfiguration(mg.hostName)
 	}
 	collection := session.Database(db.Database).Collection(mg.collectionName)
 
-	_, updateError := collection.UpdateMany(context.Background(), selector, bson.M{"$set": data})
+	_, updateError := collection.UpdateMany(mg.context(), selector, bson.M{"$set": data})
 	if updateError != nil {
 		return updateError
 	}
@@ -244,7 +282,7 @@ func (mg *MongoDAO) UpdateAll(selector map[string]interface{}, data interface{})
 
 // Update will update single entry
 func (mg *MongoDAO) Update(selector map[string]interface{}, data interface{}) error {
-	session, sessionError := GetMongoConnection(mg.hostName)
+	session, sessionError := getMongoConnection(mg.context(), mg.hostName)
 	if sessionError != nil {
 		return sessionError
 	}
@@ -254,10 +292,10 @@ func (mg *MongoDAO) Update(selector map[string]interface{}, data interface{}) er
 	}
 	db, ok := config[mg.hostName]
 	if !ok {
-		return loggermanager.Wrap("No_Configuration_Found_For_Host: " + mg.hostName)
+		return errNoConfiguration(mg.hostName)
 	}
 	collection := session.Database(db.Database).Collection(mg.collectionName)
-	_, updateError := collection.UpdateOne(context.Background(), selector, bson.M{"$set": data})
+	_, updateError := collection.UpdateOne(mg.context(), selector, bson.M{"$set": data})
 	if updateError != nil {
 		return updateError
 	}
@@ -266,7 +304,7 @@ func (mg *MongoDAO) Update(selector map[string]interface{}, data interface{}) er
 
 // GetData will return query for selector
 func (mg *MongoDAO) GetData(selector map[string]interface{}) (*gjson.Result, error) {
-	session, sessionError := GetMongoConnection(mg.hostName)
+	session, sessionError := getMongoConnection(mg.context(), mg.hostName)
 	if sessionError != nil {
 		return nil, sessionError
 	}
@@ -276,22 +314,22 @@ func (mg *MongoDAO) GetData(selector map[string]interface{}) (*gjson.Result, err
 	}
 	db, ok := config[mg.hostName]
 	if !ok {
-		return nil, loggermanager.Wrap("No_Configuration_Found_For_Host: " + mg.hostName)
+		return nil, errNoConfiguration(mg.hostName)
 	}
 	collection := session.Database(db.Database).Collection(mg.collectionName)
 
-	cur, err := collection.Find(context.Background(), selector)
+	cur, err := collection.Find(mg.context(), selector)
 	if err != nil {
-		loggermanager.LogError(err)
+		mg.log().Error("find failed", "collection", mg.collectionName, loggermanager.Err(err))
 		return nil, err
 	}
-	defer cur.Close(context.Background())
+	defer cur.Close(mg.context())
 	var results []interface{}
-	for cur.Next(context.Background()) {
+	for cur.Next(mg.context()) {
 		var result bson.M
 		err := cur.Decode(&result)
 		if err != nil {
-			loggermanager.LogError(err)
+			mg.log().Error("failed to decode document", "collection", mg.collectionName, loggermanager.Err(err))
 			return nil, err
 		}
 		results = append(results, result)
@@ -306,7 +344,7 @@ func (mg *MongoDAO) GetData(selector map[string]interface{}) (*gjson.Result, err
 
 // DeleteData will delete data given for selector
 func (mg *MongoDAO) DeleteData(selector map[string]interface{}) error {
-	session, sessionError := GetMongoConnection(mg.hostName)
+	session, sessionError := getMongoConnection(mg.context(), mg.hostName)
 	if sessionError != nil {
 		return sessionError
 	}
@@ -316,10 +354,10 @@ func (mg *MongoDAO) DeleteData(selector map[string]interface{}) error {
 	}
 	db, ok := config[mg.hostName]
 	if !ok {
-		return loggermanager.Wrap("No_Configuration_Found_For_Host: " + mg.hostName)
+		return errNoConfiguration(mg.hostName)
 	}
 	collection := session.Database(db.Database).Collection(mg.collectionName)
-	_, deleteError := collection.DeleteOne(context.Background(), selector)
+	_, deleteError := collection.DeleteOne(mg.context(), selector)
 	if deleteError != nil {
 		return deleteError
 	}
@@ -328,7 +366,7 @@ func (mg *MongoDAO) DeleteData(selector map[string]interface{}) error {
 
 // DeleteAll will delete all the matching data given for selector
 func (mg *MongoDAO) DeleteAll(selector map[string]interface{}) error {
-	session, sessionError := GetMongoConnection(mg.hostName)
+	session, sessionError := getMongoConnection(mg.context(), mg.hostName)
 	if sessionError != nil {
 		return sessionError
 	}
@@ -338,10 +376,10 @@ func (mg *MongoDAO) DeleteAll(selector map[string]interface{}) error {
 	}
 	db, ok := config[mg.hostName]
 	if !ok {
-		return loggermanager.Wrap("No_Configuration_Found_For_Host: " + mg.hostName)
+		return errNoConfiguration(mg.hostName)
 	}
 	collection := session.Database(db.Database).Collection(mg.collectionName)
-	_, deleteError := collection.DeleteMany(context.Background(), selector)
+	_, deleteError := collection.DeleteMany(mg.context(), selector)
 	if deleteError != nil {
 		return deleteError
 	}
@@ -350,7 +388,7 @@ func (mg *MongoDAO) DeleteAll(selector map[string]interface{}) error {
 
 // GetProjectedData will return query for selector and projector
 func (mg *MongoDAO) GetProjectedData(selector map[string]interface{}, projector map[string]interface{}) (*gjson.Result, error) {
-	session, sessionError := GetMongoConnection(mg.hostName)
+	session, sessionError := getMongoConnection(mg.context(), mg.hostName)
 	if sessionError != nil {
 		return nil, sessionError
 	}
@@ -360,23 +398,23 @@ func (mg *MongoDAO) GetProjectedData(selector map[string]interface{}, projector
 	}
 	db, ok := config[mg.hostName]
 	if !ok {
-		return nil, loggermanager.Wrap("No_Configuration_Found_For_Host: " + mg.hostName)
+		return nil, errNoConfiguration(mg.hostName)
 	}
 	collection := session.Database(db.Database).Collection(mg.collectionName)
 	ops := &options.FindOptions{}
 	ops.Projection = projector
-	cur, err := collection.Find(context.Background(), selector, ops)
+	cur, err := collection.Find(mg.context(), selector, ops)
 	if err != nil {
-		loggermanager.LogError(err)
+		mg.log().Error("find failed", "collection", mg.collectionName, loggermanager.Err(err))
 		return nil, err
 	}
-	defer cur.Close(context.Background())
+	defer cur.Close(mg.context())
 	var results []interface{}
-	for cur.Next(context.Background()) {
+	for cur.Next(mg.context()) {
 		var result bson.M
 		err := cur.Decode(&result)
 		if err != nil {
-			loggermanager.LogError(err)
+			mg.log().Error("failed to decode document", "collection", mg.collectionName, loggermanager.Err(err))
 			return nil, err
 		}
 		results = append(results, result)
@@ -392,7 +430,7 @@ func (mg *MongoDAO) GetProjectedData(selector map[string]interface{}, projector
 
 // GetAggregateData - return result using aggregation query
 func (mg *MongoDAO) GetAggregateData(selector interface{}) (*gjson.Result, error) {
-	session, sessionError := GetMongoConnection(mg.hostName)
+	session, sessionError := getMongoConnection(mg.context(), mg.hostName)
 	if sessionError != nil {
 		return nil, sessionError
 	}
@@ -402,21 +440,21 @@ func (mg *MongoDAO) GetAggregateData(selector interface{}) (*gjson.Result, error
 	}
 	db, ok := config[mg.hostName]
 	if !ok {
-		return nil, loggermanager.Wrap("No_Configuration_Found_For_Host: " + mg.hostName)
+		return nil, errNoConfiguration(mg.hostName)
 	}
 	collection := session.Database(db.Database).Collection(mg.collectionName)
-	cur, err := collection.Aggregate(context.Background(), selector)
+	cur, err := collection.Aggregate(mg.context(), selector)
 	if err != nil {
-		loggermanager.LogError(err)
+		mg.log().Error("aggregate failed", "collection", mg.collectionName, loggermanager.Err(err))
 		return nil, err
 	}
-	defer cur.Close(context.Background())
+	defer cur.Close(mg.context())
 	var results []interface{}
-	for cur.Next(context.Background()) {
+	for cur.Next(mg.context()) {
 		var result bson.M
 		err := cur.Decode(&result)
 		if err != nil {
-			loggermanager.LogError(err)
+			mg.log().Error("failed to decode document", "collection", mg.collectionName, loggermanager.Err(err))
 			return nil, err
 		}
 		results = append(results, result)
@@ -435,7 +473,7 @@ func (mg *MongoDAO) GetAggregateData(selector interface{}) (*gjson.Result, error
 //
 // If no document is upserted the object id returned will be empty string.
 func (mg *MongoDAO) UpsertWithID(selector map[string]interface{}, data interface{}) (string, error) {
-	session, sessionError := GetMongoConnection(mg.hostName)
+	session, sessionError := getMongoConnection(mg.context(), mg.hostName)
 	if sessionError != nil {
 		return "", sessionError
 	}
@@ -445,12 +483,12 @@ func (mg *MongoDAO) UpsertWithID(selector map[string]interface{}, data interface
 	}
 	db, ok := config[mg.hostName]
 	if !ok {
-		return "", loggermanager.Wrap("No_Configuration_Found_For_Host: " + mg.hostName)
+		return "", errNoConfiguration(mg.hostName)
 	}
 	collection := session.Database(db.Database).Collection(mg.collectionName)
 	ops := options.UpdateOptions{}
 	ops.SetUpsert(true)
-	upsertRes, updateError := collection.UpdateOne(context.Background(), selector, bson.M{"$set": data}, &ops)
+	upsertRes, updateError := collection.UpdateOne(mg.context(), selector, bson.M{"$set": data}, &ops)
 	if updateError != nil {
 		return "", updateError
 	}
@@ -488,7 +526,7 @@ func (mg *MongoDAO) Upsert(selector map[string]interface{}, data interface{}) er
 
 // PushData - append in array
 func (mg *MongoDAO) PushData(selector map[string]interface{}, data interface{}) error {
-	session, sessionError := GetMongoConnection(mg.hostName)
+	session, sessionError := getMongoConnection(mg.context(), mg.hostName)
 	if sessionError != nil {
 		return sessionError
 	}
@@ -498,10 +536,10 @@ func (mg *MongoDAO) PushData(selector map[string]interface{}, data interface{})
 	}
 	db, ok := config[mg.hostName]
 	if !ok {
-		return loggermanager.Wrap("No_Configuration_Found_For_Host: " + mg.hostName)
+		return errNoConfiguration(mg.hostName)
 	}
 	collection := session.Database(db.Database).Collection(mg.collectionName)
-	_, updateError := collection.UpdateMany(context.Background(), selector, bson.M{"$push": data})
+	_, updateError := collection.UpdateMany(mg.context(), selector, bson.M{"$push": data})
 	if updateError != nil {
 		return updateError
 	}
@@ -511,7 +549,7 @@ func (mg *MongoDAO) PushData(selector map[string]interface{}, data interface{})
 
 // CustomUpdate - CustomUpdate
 func (mg *MongoDAO) CustomUpdate(selector map[string]interface{}, data interface{}) error {
-	session, sessionError := GetMongoConnection(mg.hostName)
+	session, sessionError := getMongoConnection(mg.context(), mg.hostName)
 	if sessionError != nil {
 		return sessionError
 	}
@@ -521,10 +559,10 @@ func (mg *MongoDAO) CustomUpdate(selector map[string]interface{}, data interface
 	}
 	db, ok := config[mg.hostName]
 	if !ok {
-		return loggermanager.Wrap("No_Configuration_Found_For_Host: " + mg.hostName)
+		return errNoConfiguration(mg.hostName)
 	}
 	collection := session.Database(db.Database).Collection(mg.collectionName)
-	_, updateError := collection.UpdateMany(context.Background(), selector, data)
+	_, updateError := collection.UpdateMany(mg.context(), selector, data)
 	if updateError != nil {
 		return updateError
 	}
@@ -533,7 +571,7 @@ func (mg *MongoDAO) CustomUpdate(selector map[string]interface{}, data interface
 
 // CustomUpdateOne - CustomUpdateOne
 func (mg *MongoDAO) CustomUpdateOne(selector map[string]interface{}, data interface{}) error {
-	session, sessionError := GetMongoConnection(mg.hostName)
+	session, sessionError := getMongoConnection(mg.context(), mg.hostName)
 	if sessionError != nil {
 		return sessionError
 	}
@@ -543,10 +581,10 @@ func (mg *MongoDAO) CustomUpdateOne(selector map[string]interface{}, data interf
 	}
 	db, ok := config[mg.hostName]
 	if !ok {
-		return loggermanager.Wrap("No_Configuration_Found_For_Host: " + mg.hostName)
+		return errNoConfiguration(mg.hostName)
 	}
 	collection := session.Database(db.Database).Collection(mg.collectionName)
-	_, updateError := collection.UpdateOne(context.Background(), selector, data)
+	_, updateError := collection.UpdateOne(mg.context(), selector, data)
 	if updateError != nil {
 		return updateError
 	}
@@ -560,7 +598,7 @@ func (mg *MongoDAO) BulkSaveData(data []interface{}) error {
 	if checkBulkInput(data) {
 		return nil
 	}
-	session, sessionError := GetMongoConnection(mg.hostName)
+	session, sessionError := getMongoConnection(mg.context(), mg.hostName)
 	if sessionError != nil {
 		return sessionError
 	}
@@ -570,7 +608,7 @@ func (mg *MongoDAO) BulkSaveData(data []interface{}) error {
 	}
 	db, ok := config[mg.hostName]
 	if !ok {
-		return loggermanager.Wrap("No_Configuration_Found_For_Host: " + mg.hostName)
+		return errNoConfiguration(mg.hostName)
 	}
 	collection := session.Database(db.Database).Collection(mg.collectionName)
 	opts := &options.BulkWriteOptions{}
@@ -581,9 +619,9 @@ func (mg *MongoDAO) BulkSaveData(data []interface{}) error {
 		model.SetDocument(data[i])
 		models = append(models, model)
 	}
-	_, insertError := collection.BulkWrite(context.Background(), models, opts)
+	_, insertError := collection.BulkWrite(mg.context(), models, opts)
 	if insertError != nil {
-		loggermanager.LogError(insertError)
+		mg.log().Error("bulk write failed", "collection", mg.collectionName, loggermanager.Err(insertError))
 		return insertError
 	}
 
@@ -595,7 +633,7 @@ func (mg *MongoDAO) BulkUpdateData(data []interface{}) error {
 	if checkBulkInput(data) {
 		return nil
 	}
-	session, sessionError := GetMongoConnection(mg.hostName)
+	session, sessionError := getMongoConnection(mg.context(), mg.hostName)
 	if sessionError != nil {
 		return sessionError
 	}
@@ -605,7 +643,7 @@ func (mg *MongoDAO) BulkUpdateData(data []interface{}) error {
 	}
 	db, ok := config[mg.hostName]
 	if !ok {
-		return loggermanager.Wrap("No_Configuration_Found_For_Host: " + mg.hostName)
+		return errNoConfiguration(mg.hostName)
 	}
 	collection := session.Database(db.Database).Collection(mg.collectionName)
 	opts := &options.BulkWriteOptions{}
@@ -619,9 +657,9 @@ func (mg *MongoDAO) BulkUpdateData(data []interface{}) error {
 		models = append(models, model)
 	}
 
-	_, insertError := collection.BulkWrite(context.Background(), models, opts)
+	_, insertError := collection.BulkWrite(mg.context(), models, opts)
 	if insertError != nil {
-		loggermanager.LogError(insertError)
+		mg.log().Error("bulk write failed", "collection", mg.collectionName, loggermanager.Err(insertError))
 		return insertError
 	}
 	return nil
@@ -632,7 +670,7 @@ func (mg *MongoDAO) BulkDeleteData(data []interface{}) error {
 	if checkBulkInput(data) {
 		return nil
 	}
-	session, sessionError := GetMongoConnection(mg.hostName)
+	session, sessionError := getMongoConnection(mg.context(), mg.hostName)
 	if sessionError != nil {
 		return sessionError
 	}
@@ -642,7 +680,7 @@ func (mg *MongoDAO) BulkDeleteData(data []interface{}) error {
 	}
 	db, ok := config[mg.hostName]
 	if !ok {
-		return loggermanager.Wrap("No_Configuration_Found_For_Host: " + mg.hostName)
+		return errNoConfiguration(mg.hostName)
 	}
 	collection := session.Database(db.Database).Collection(mg.collectionName)
 	opts := &options.BulkWriteOptions{}
@@ -653,9 +691,9 @@ func (mg *MongoDAO) BulkDeleteData(data []interface{}) error {
 		model.SetFilter(data[i])
 		models = append(models, model)
 	}
-	_, insertError := collection.BulkWrite(context.Background(), models, opts)
+	_, insertError := collection.BulkWrite(mg.context(), models, opts)
 	if insertError != nil {
-		loggermanager.LogError(insertError)
+		mg.log().Error("bulk write failed", "collection", mg.collectionName, loggermanager.Err(insertError))
 		return insertError
 	}
 	return nil
@@ -666,7 +704,7 @@ func (mg *MongoDAO) BulkUpsertData(data []interface{}) error {
 	if checkBulkInput(data) {
 		return nil
 	}
-	session, sessionError := GetMongoConnection(mg.hostName)
+	session, sessionError := getMongoConnection(mg.context(), mg.hostName)
 	if sessionError != nil {
 		return sessionError
 	}
@@ -676,7 +714,7 @@ func (mg *MongoDAO) BulkUpsertData(data []interface{}) error {
 	}
 	db, ok := config[mg.hostName]
 	if !ok {
-		return loggermanager.Wrap("No_Configuration_Found_For_Host: " + mg.hostName)
+		return errNoConfiguration(mg.hostName)
 	}
 	collection := session.Database(db.Database).Collection(mg.collectionName)
 	opts := &options.BulkWriteOptions{}
@@ -691,14 +729,18 @@ func (mg *MongoDAO) BulkUpsertData(data []interface{}) error {
 		models = append(models, model)
 	}
 
-	_, insertError := collection.BulkWrite(context.Background(), models, opts)
+	_, insertError := collection.BulkWrite(mg.context(), models, opts)
 	if insertError != nil {
-		loggermanager.LogError(insertError)
+		mg.log().Error("bulk write failed", "collection", mg.collectionName, loggermanager.Err(insertError))
 		return insertError
 	}
 	return nil
 }
 
+func errNoConfiguration(hostName string) error {
+	return loggermanager.New(loggermanager.CodeNoConfigurationFound, "No_Configuration_Found_For_Host: "+hostName).WithDetail("host", hostName)
+}
+
 func checkBulkInput(d []interface{}) bool {
 	return len(d) == 0
 }
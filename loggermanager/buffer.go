@@ -0,0 +1,330 @@
+package loggermanager
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultBufferMaxEntries = 1000
+
+// BufferOptions controls which entries a request buffer holds and when they are written
+type BufferOptions struct {
+	// Threshold is the level from which entries are written at once, lower ones are held. Default "warn".
+	Threshold string
+	// Latency flushes requests taking at least this long, 0 disables it
+	Latency time.Duration
+	// MaxEntries held per request, the oldest are dropped beyond it. Default 1000.
+	MaxEntries int
+	// FlushStatus is the lowest response status flushing the buffer in BufferHandler, default 500
+	FlushStatus int
+}
+
+// RequestBuffer holds the low level entries of one request until it is known how the request ended.
+// Held entries bypass module levels, so debug entries of failed requests are written even in production.
+type RequestBuffer struct {
+	opts      BufferOptions
+	threshold zapcore.Level
+	start     time.Time
+
+	mu      sync.Mutex
+	entries []bufferedEntry
+	dropped int
+	state   int
+}
+
+// states of a RequestBuffer
+const (
+	bufferHolding   = iota
+	bufferFlushed   // later entries below the threshold are written at once, skipping module levels
+	bufferDiscarded // later entries are written as usual
+)
+
+type bufferedEntry struct {
+	core   *bufferCore // writes the entry on flush
+	ent    zapcore.Entry
+	fields []zapcore.Field
+}
+
+// ContextWithBuffer returns a context whose logger, see FromContext, holds entries below opts.Threshold.
+// A logger put in ctx before, see ContextWithLogger, keeps its name and fields and still writes the entries.
+// Call Finish on the returned buffer when the request ends.
+func ContextWithBuffer(ctx context.Context, opts BufferOptions) (context.Context, *RequestBuffer) {
+	threshold := zapcore.WarnLevel
+	if opts.Threshold != "" {
+		if lvl, err := parseLevel(opts.Threshold); err == nil {
+			threshold = lvl
+		}
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = defaultBufferMaxEntries
+	}
+	if opts.FlushStatus <= 0 {
+		opts.FlushStatus = http.StatusInternalServerError
+	}
+	b := &RequestBuffer{opts: opts, threshold: threshold, start: time.Now()}
+	return ContextWithLogger(ctx, bufferLogger(ctx, b)), b
+}
+
+// bufferLogger returns a logger holding entries in b, over the logger of ctx if any
+func bufferLogger(ctx context.Context, b *RequestBuffer) Logger {
+	var l Logger
+	if ctx != nil {
+		l, _ = ctx.Value(loggerKey).(Logger)
+	}
+	switch cl := l.(type) {
+	case nil:
+	case *zapLogger:
+		return newZapLogger(cl.base.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return &bufferCore{b: b, next: core}
+		})))
+	case *lazyLogger:
+		// resolved against the package logger on write, like the lazy logger
+		bl := NewZapLogger(zap.New(&bufferCore{b: b}, zap.AddCaller()))
+		if cl.name != "" {
+			bl = bl.Named(cl.name)
+		}
+		if len(cl.kv) > 0 {
+			bl = bl.With(cl.kv...)
+		}
+		return bl
+	default:
+		return NewZapLogger(zap.New(&bufferCore{b: b, logger: l}, zap.AddCaller()))
+	}
+	return NewZapLogger(zap.New(&bufferCore{b: b}, zap.AddCaller()))
+}
+
+// Finish writes the held entries if err is not nil or the request was too slow, otherwise discards them.
+// It reports whether the entries were written.
+func (b *RequestBuffer) Finish(err error) bool {
+	return b.finish(err != nil)
+}
+
+func (b *RequestBuffer) finish(failed bool) bool {
+	if failed || (b.opts.Latency > 0 && time.Since(b.start) >= b.opts.Latency) {
+		b.Flush()
+		return true
+	}
+	b.Discard()
+	return false
+}
+
+// Flush writes the held entries now, entries logged afterwards are written at once
+func (b *RequestBuffer) Flush() {
+	b.mu.Lock()
+	entries, dropped := b.entries, b.dropped
+	b.entries, b.dropped, b.state = nil, 0, bufferFlushed
+	b.mu.Unlock()
+
+	if dropped > 0 && len(entries) > 0 {
+		entries[0].core.write(true, zapcore.Entry{Level: zapcore.WarnLevel, Time: time.Now(), Message: "request log buffer overflowed"},
+			[]zapcore.Field{zap.Int("dropped", dropped)})
+	}
+	for _, e := range entries {
+		e.core.write(true, e.ent, e.fields)
+	}
+}
+
+// Discard forgets the held entries, entries logged afterwards are written as usual
+func (b *RequestBuffer) Discard() {
+	b.mu.Lock()
+	b.entries, b.dropped, b.state = nil, 0, bufferDiscarded
+	b.mu.Unlock()
+}
+
+// Len returns the number of held entries
+func (b *RequestBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.entries)
+}
+
+// hold keeps the entry while the buffer is holding and returns the state
+func (b *RequestBuffer) hold(c *bufferCore, ent zapcore.Entry, fields []zapcore.Field) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != bufferHolding {
+		return b.state
+	}
+	if len(b.entries) >= b.opts.MaxEntries {
+		b.entries = b.entries[1:]
+		b.dropped++
+	}
+	b.entries = append(b.entries, bufferedEntry{core: c, ent: ent, fields: fields})
+	return bufferHolding
+}
+
+// pipelineCore returns the core of the package logger, nil if it is not zap based
+func pipelineCore() zapcore.Core {
+	if zl, ok := GetLogger().(*zapLogger); ok {
+		return zl.base.Core()
+	}
+	return nil
+}
+
+// writeEntry writes to core, or hands the entry to l, the package logger if nil, when core is nil
+func writeEntry(core zapcore.Core, l Logger, ent zapcore.Entry, fields []zapcore.Field) {
+	if core == nil {
+		// not zap based, hand the entry to the Logger
+		kv := make([]interface{}, len(fields))
+		for i := range fields {
+			kv[i] = fields[i]
+		}
+		if l == nil {
+			l = GetLogger()
+		}
+		if ent.LoggerName != "" {
+			l = l.Named(ent.LoggerName)
+		}
+		switch {
+		case ent.Level <= zapcore.DebugLevel:
+			l.Debug(ent.Message, kv...)
+		case ent.Level == zapcore.InfoLevel:
+			l.Info(ent.Message, kv...)
+		case ent.Level == zapcore.WarnLevel:
+			l.Warn(ent.Message, kv...)
+		default:
+			l.Error(ent.Message, kv...)
+		}
+		return
+	}
+	if ce := core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// bufferCore holds entries below the threshold and passes the others to the logger it wraps,
+// the package logger unless next or logger is set
+type bufferCore struct {
+	b      *RequestBuffer
+	fields []zapcore.Field
+	next   zapcore.Core // core of a zap based context logger
+	logger Logger       // context logger which is not zap based
+}
+
+func (c *bufferCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *bufferCore) With(fields []zapcore.Field) zapcore.Core {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	return &bufferCore{b: c.b, fields: append(append(all, c.fields...), fields...), next: c.next, logger: c.logger}
+}
+
+func (c *bufferCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *bufferCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(append(all, c.fields...), fields...)
+	if ent.Level < c.b.threshold {
+		switch c.b.hold(c, ent, all) {
+		case bufferHolding:
+			return nil
+		case bufferFlushed:
+			c.write(true, ent, all)
+			return nil
+		}
+	}
+	if ent.Level >= zapcore.ErrorLevel {
+		// errors need the context of what led to them
+		c.b.Flush()
+	}
+	c.write(false, ent, all)
+	return nil
+}
+
+// write passes the entry to the wrapped logger, without its module levels if unleveled
+func (c *bufferCore) write(unleveled bool, ent zapcore.Entry, fields []zapcore.Field) {
+	core := c.next
+	if core == nil && c.logger == nil {
+		core = pipelineCore()
+	}
+	if lc, ok := core.(*levelCore); ok && unleveled {
+		core = lc.Core
+	}
+	writeEntry(core, c.logger, ent, fields)
+}
+
+func (c *bufferCore) Sync() error {
+	if c.next != nil {
+		return c.next.Sync()
+	}
+	return Sync()
+}
+
+// BufferHandler buffers the low level entries of every request, see ContextWithBuffer.
+// They are written if the response status is at least opts.FlushStatus, the handler panics
+// or the request is slower than opts.Latency.
+func BufferHandler(next http.Handler, opts BufferOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, b := ContextWithBuffer(r.Context(), opts)
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			if v := recover(); v != nil {
+				b.Flush()
+				panic(v)
+			}
+			b.finish(sw.status >= b.opts.FlushStatus)
+		}()
+		next.ServeHTTP(sw, r.WithContext(ctx))
+	})
+}
+
+// statusWriter remembers the response status
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush lets streaming handlers flush through the wrapper
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets websocket upgrades take over the connection, the handler owns the response afterwards
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && !w.wroteHeader {
+		w.status, w.wroteHeader = http.StatusSwitchingProtocols, true
+	}
+	return conn, rw, err
+}
+
+func (w *statusWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap gives http.ResponseController the other methods of the wrapped writer
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
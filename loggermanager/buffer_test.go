@@ -0,0 +1,185 @@
+package loggermanager
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observeAtWarn installs a logger whose module levels drop everything below warn
+func observeAtWarn(t *testing.T) *observer.ObservedLogs {
+	prev := GetLogger()
+	core, logs := observer.New(zapcore.DebugLevel)
+	SetLogger(NewZapLogger(zap.New(&levelCore{Core: core, reg: newLevelRegistry(zapcore.WarnLevel)})))
+	t.Cleanup(func() { SetLogger(prev) })
+	return logs
+}
+
+func TestRequestBufferDiscardsOnSuccess(t *testing.T) {
+	logs := observeAtWarn(t)
+
+	ctx, b := ContextWithBuffer(context.Background(), BufferOptions{})
+	LogDebugCtx(ctx, "loading")
+	LogInfoCtx(ctx, "loaded")
+	LogWarnCtx(ctx, "slow disk")
+	if b.Len() != 2 || logs.Len() != 1 {
+		t.Fatalf("held %d, written %d", b.Len(), logs.Len())
+	}
+	if b.Finish(nil) {
+		t.Error("Finish(nil) flushed")
+	}
+	LogInfoCtx(ctx, "after finish")
+	if logs.Len() != 1 {
+		t.Errorf("written %v", logs.All())
+	}
+}
+
+func TestRequestBufferFlushesOnError(t *testing.T) {
+	logs := observeAtWarn(t)
+
+	ctx, b := ContextWithBuffer(ContextWithRequestID(context.Background(), "req-3"), BufferOptions{})
+	LogDebugCtx(ctx, "query", "id", 7)
+	LogInfoCtx(ctx, "cache miss")
+	if !b.Finish(errors.New("failed")) {
+		t.Fatal("Finish(err) did not flush")
+	}
+	LogDebugCtx(ctx, "cleanup")
+
+	msgs := []string{"query", "cache miss", "cleanup"}
+	entries := logs.All()
+	if len(entries) != len(msgs) {
+		t.Fatalf("written %v", entries)
+	}
+	for i, e := range entries {
+		if e.Message != msgs[i] || e.ContextMap()[FieldRequestID] != "req-3" {
+			t.Errorf("entry %d = %q %v", i, e.Message, e.ContextMap())
+		}
+	}
+	if entries[0].Level != zapcore.DebugLevel || entries[0].Caller.File == "" {
+		t.Errorf("entry lost level or caller: %+v", entries[0].Entry)
+	}
+}
+
+func TestRequestBufferErrorEntryFlushes(t *testing.T) {
+	logs := observeAtWarn(t)
+
+	ctx, _ := ContextWithBuffer(context.Background(), BufferOptions{MaxEntries: 2})
+	for _, msg := range []string{"one", "two", "three"} {
+		LogInfoCtx(ctx, msg)
+	}
+	LogErrorCtx(ctx, "failed")
+
+	var got []string
+	for _, e := range logs.All() {
+		got = append(got, e.Message)
+	}
+	want := []string{"request log buffer overflowed", "two", "three", "failed"}
+	if len(got) != len(want) {
+		t.Fatalf("written %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("written %v, want %v", got, want)
+			break
+		}
+	}
+	if d := logs.All()[0].ContextMap()["dropped"]; d != int64(1) {
+		t.Errorf("dropped = %v", d)
+	}
+}
+
+func TestRequestBufferLatency(t *testing.T) {
+	logs := observeAtWarn(t)
+
+	ctx, b := ContextWithBuffer(context.Background(), BufferOptions{Latency: time.Millisecond})
+	LogInfoCtx(ctx, "slow")
+	time.Sleep(2 * time.Millisecond)
+	if !b.Finish(nil) || logs.Len() != 1 {
+		t.Errorf("slow request not flushed, written %d", logs.Len())
+	}
+}
+
+func TestBufferHandler(t *testing.T) {
+	logs := observeAtWarn(t)
+
+	h := BufferHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LogInfoCtx(r.Context(), "handling", "path", r.URL.Path)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}), BufferOptions{Threshold: "error"})
+
+	for _, path := range []string{"/ok", "/fail"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	entries := logs.All()
+	if len(entries) != 1 || entries[0].ContextMap()["path"] != "/fail" {
+		t.Errorf("written %v", entries)
+	}
+}
+
+func TestBufferHandlerHijack(t *testing.T) {
+	logs := observeAtWarn(t)
+
+	h := BufferHandler(RecoverHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LogInfoCtx(r.Context(), "upgrading")
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("middleware hides http.Hijacker")
+			http.Error(w, "no hijack", http.StatusInternalServerError)
+			return
+		}
+		conn, rw, err := hj.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		rw.Flush()
+	})), BufferOptions{})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "hijacked" {
+		t.Errorf("body = %q", body)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("written %v", logs.All())
+	}
+}
+
+func TestRequestBufferKeepsContextLogger(t *testing.T) {
+	observeAtWarn(t)
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := NewZapLogger(zap.New(core)).Named("api").With("route", "/users")
+
+	ctx, b := ContextWithBuffer(ContextWithLogger(context.Background(), l), BufferOptions{})
+	LogDebugCtx(ctx, "query")
+	LogWarnCtx(ctx, "slow")
+	b.Flush()
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("written %v", entries)
+	}
+	for _, e := range entries {
+		if e.LoggerName != "api" || e.ContextMap()["route"] != "/users" {
+			t.Errorf("%q lost the context logger: %q %v", e.Message, e.LoggerName, e.ContextMap())
+		}
+	}
+}
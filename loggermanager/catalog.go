@@ -0,0 +1,232 @@
+package loggermanager
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// GRPCCode mirrors google.golang.org/grpc/codes so corelib does not depend on grpc.
+// Convert with codes.Code(c).
+type GRPCCode uint32
+
+// gRPC status codes
+const (
+	GRPCOK GRPCCode = iota
+	GRPCCanceled
+	GRPCUnknown
+	GRPCInvalidArgument
+	GRPCDeadlineExceeded
+	GRPCNotFound
+	GRPCAlreadyExists
+	GRPCPermissionDenied
+	GRPCResourceExhausted
+	GRPCFailedPrecondition
+	GRPCAborted
+	GRPCOutOfRange
+	GRPCUnimplemented
+	GRPCInternal
+	GRPCUnavailable
+	GRPCDataLoss
+	GRPCUnauthenticated
+)
+
+// Codes of the failures corelib reports itself. Many functions still return errors of the
+// mongo and redis drivers, encoders or the file system as they are; Classify maps those
+// it knows, such as the mongo errors below, the rest keep CodeUnknown (500).
+//
+//	Code                      HTTP  gRPC                Returned by
+//	NO_HOST_FOUND             404   NotFound            mongodb.DeleteSession
+//	DUPLICATE_HOSTNAME        409   AlreadyExists       mongodb.InitNewSession
+//	MONGO_INIT_NOT_DONE       503   Unavailable         mongodb.GetMongoConnection
+//	SESSION_NOT_FOUND         503   Unavailable         mongodb.GetMongoConnection
+//	NO_CONFIGURATION_FOUND    500   FailedPrecondition  mongodb.MongoDAO methods
+//	INVALID_ARGUMENT          400   InvalidArgument     mongodb GridFS helpers, LevelHandler, cachemanager.NewCache
+//	CACHE_MARSHAL_FAILED      500   Internal            cachemanager SaveFile, TypedCache Set
+//	CACHE_FILE_WRITE_FAILED   500   Internal            cachemanager SaveFile
+//	CACHE_FILE_READ_FAILED    500   Internal            cachemanager LoadFile
+//	CACHE_FILE_DECODE_FAILED  500   DataLoss            cachemanager LoadFile
+//	REDIS_CONNECTION_FAILED   503   Unavailable         cachemanager.SetupRedisCache
+//	CACHE_UNAVAILABLE         503   Unavailable         cachemanager.RedisCache SaveFile, LoadFile
+//	CACHE_MISS                404   NotFound            cachemanager.TypedCache.Get
+//	CACHE_DECODE_FAILED       500   DataLoss            cachemanager.TypedCache.Get
+//	DOCUMENT_NOT_FOUND        404   NotFound            mongo.ErrNoDocuments, via Classify
+//	DUPLICATE_KEY             409   AlreadyExists       mongo duplicate key errors, via Classify
+//	DATABASE_TIMEOUT          504   DeadlineExceeded    mongo timeouts, via Classify
+//	DATABASE_UNAVAILABLE      503   Unavailable         mongo network errors, via Classify
+//	INVALID_TOKEN             401   Unauthenticated     authmanager.DecodeJWTToken
+//	TOKEN_EXPIRED             401   Unauthenticated     authmanager.DecodeJWTToken
+//	INVALID_CLAIMS            401   Unauthenticated     authmanager.DecodeJWTToken
+//	INVALID_CONFIG            500   FailedPrecondition  loggermanager config and sinks
+//	LOG_SHIPPING_FAILED       502   Unavailable         loggermanager http sink
+//	PANIC                     500   Internal            loggermanager.RecoverError, RecoverHandler
+const (
+	CodeNoHostFound           Code = "NO_HOST_FOUND"
+	CodeDuplicateHostname     Code = "DUPLICATE_HOSTNAME"
+	CodeMongoInitNotDone      Code = "MONGO_INIT_NOT_DONE"
+	CodeSessionNotFound       Code = "SESSION_NOT_FOUND"
+	CodeNoConfigurationFound  Code = "NO_CONFIGURATION_FOUND"
+	CodeInvalidArgument       Code = "INVALID_ARGUMENT"
+	CodeCacheMarshalFailed    Code = "CACHE_MARSHAL_FAILED"
+	CodeCacheFileWriteFailed  Code = "CACHE_FILE_WRITE_FAILED"
+	CodeCacheFileReadFailed   Code = "CACHE_FILE_READ_FAILED"
+	CodeCacheFileDecodeFailed Code = "CACHE_FILE_DECODE_FAILED"
+	CodeRedisConnectionFailed Code = "REDIS_CONNECTION_FAILED"
+	CodeCacheUnavailable      Code = "CACHE_UNAVAILABLE"
+	CodeCacheMiss             Code = "CACHE_MISS"
+	CodeCacheDecodeFailed     Code = "CACHE_DECODE_FAILED"
+	CodeDocumentNotFound      Code = "DOCUMENT_NOT_FOUND"
+	CodeDuplicateKey          Code = "DUPLICATE_KEY"
+	CodeDatabaseTimeout       Code = "DATABASE_TIMEOUT"
+	CodeDatabaseUnavailable   Code = "DATABASE_UNAVAILABLE"
+	CodeInvalidToken          Code = "INVALID_TOKEN"
+	CodeTokenExpired          Code = "TOKEN_EXPIRED"
+	CodeInvalidClaims         Code = "INVALID_CLAIMS"
+	CodeInvalidConfig         Code = "INVALID_CONFIG"
+	CodeLogShippingFailed     Code = "LOG_SHIPPING_FAILED"
+	CodePanic                 Code = "PANIC"
+)
+
+// Sentinel errors, compare with errors.Is(err, ErrNoHostFound)
+var (
+	ErrNoHostFound           = Register(CodeInfo{Code: CodeNoHostFound, HTTPStatus: http.StatusNotFound, GRPCCode: GRPCNotFound, Messages: en("The requested database host does not exist.")})
+	ErrDuplicateHostname     = Register(CodeInfo{Code: CodeDuplicateHostname, HTTPStatus: http.StatusConflict, GRPCCode: GRPCAlreadyExists, Messages: en("A database host with this name is already configured.")})
+	ErrMongoInitNotDone      = Register(CodeInfo{Code: CodeMongoInitNotDone, HTTPStatus: http.StatusServiceUnavailable, GRPCCode: GRPCUnavailable, Messages: en("The database is not ready yet, please try again later.")})
+	ErrSessionNotFound       = Register(CodeInfo{Code: CodeSessionNotFound, HTTPStatus: http.StatusServiceUnavailable, GRPCCode: GRPCUnavailable, Messages: en("The database is currently unavailable, please try again later.")})
+	ErrNoConfigurationFound  = Register(CodeInfo{Code: CodeNoConfigurationFound, HTTPStatus: http.StatusInternalServerError, GRPCCode: GRPCFailedPrecondition, Messages: en("The service is not configured correctly.")})
+	ErrInvalidArgument       = Register(CodeInfo{Code: CodeInvalidArgument, HTTPStatus: http.StatusBadRequest, GRPCCode: GRPCInvalidArgument, Messages: en("The request is invalid.")})
+	ErrCacheMarshalFailed    = Register(CodeInfo{Code: CodeCacheMarshalFailed, HTTPStatus: http.StatusInternalServerError, GRPCCode: GRPCInternal, Messages: en("Something went wrong, please try again later.")})
+	ErrCacheFileWriteFailed  = Register(CodeInfo{Code: CodeCacheFileWriteFailed, HTTPStatus: http.StatusInternalServerError, GRPCCode: GRPCInternal, Messages: en("Something went wrong, please try again later.")})
+	ErrCacheFileReadFailed   = Register(CodeInfo{Code: CodeCacheFileReadFailed, HTTPStatus: http.StatusInternalServerError, GRPCCode: GRPCInternal, Messages: en("Something went wrong, please try again later.")})
+	ErrCacheFileDecodeFailed = Register(CodeInfo{Code: CodeCacheFileDecodeFailed, HTTPStatus: http.StatusInternalServerError, GRPCCode: GRPCDataLoss, Messages: en("Something went wrong, please try again later.")})
+	ErrRedisConnectionFailed = Register(CodeInfo{Code: CodeRedisConnectionFailed, HTTPStatus: http.StatusServiceUnavailable, GRPCCode: GRPCUnavailable, Messages: en("The service is temporarily unavailable, please try again later.")})
+	ErrCacheUnavailable      = Register(CodeInfo{Code: CodeCacheUnavailable, HTTPStatus: http.StatusServiceUnavailable, GRPCCode: GRPCUnavailable, Messages: en("The service is temporarily unavailable, please try again later.")})
+	ErrCacheMiss             = Register(CodeInfo{Code: CodeCacheMiss, HTTPStatus: http.StatusNotFound, GRPCCode: GRPCNotFound, Messages: en("The requested item does not exist.")})
+	ErrCacheDecodeFailed     = Register(CodeInfo{Code: CodeCacheDecodeFailed, HTTPStatus: http.StatusInternalServerError, GRPCCode: GRPCDataLoss, Messages: en("Something went wrong, please try again later.")})
+	ErrDocumentNotFound      = Register(CodeInfo{Code: CodeDocumentNotFound, HTTPStatus: http.StatusNotFound, GRPCCode: GRPCNotFound, Messages: en("The requested item does not exist.")})
+	ErrDuplicateKey          = Register(CodeInfo{Code: CodeDuplicateKey, HTTPStatus: http.StatusConflict, GRPCCode: GRPCAlreadyExists, Messages: en("The item already exists.")})
+	ErrDatabaseTimeout       = Register(CodeInfo{Code: CodeDatabaseTimeout, HTTPStatus: http.StatusGatewayTimeout, GRPCCode: GRPCDeadlineExceeded, Messages: en("The request took too long, please try again later.")})
+	ErrDatabaseUnavailable   = Register(CodeInfo{Code: CodeDatabaseUnavailable, HTTPStatus: http.StatusServiceUnavailable, GRPCCode: GRPCUnavailable, Messages: en("The database is currently unavailable, please try again later.")})
+	ErrInvalidToken          = Register(CodeInfo{Code: CodeInvalidToken, HTTPStatus: http.StatusUnauthorized, GRPCCode: GRPCUnauthenticated, Messages: en("Your session is invalid or has expired, please sign in again.")})
+	ErrTokenExpired          = Register(CodeInfo{Code: CodeTokenExpired, HTTPStatus: http.StatusUnauthorized, GRPCCode: GRPCUnauthenticated, Messages: en("Your session has expired, please sign in again.")})
+	ErrInvalidClaims         = Register(CodeInfo{Code: CodeInvalidClaims, HTTPStatus: http.StatusUnauthorized, GRPCCode: GRPCUnauthenticated, Messages: en("Your session is invalid, please sign in again.")})
+	ErrInvalidConfig         = Register(CodeInfo{Code: CodeInvalidConfig, HTTPStatus: http.StatusInternalServerError, GRPCCode: GRPCFailedPrecondition, Messages: en("The service is not configured correctly.")})
+	ErrLogShippingFailed     = Register(CodeInfo{Code: CodeLogShippingFailed, HTTPStatus: http.StatusBadGateway, GRPCCode: GRPCUnavailable, Messages: en("Something went wrong, please try again later.")})
+	ErrPanic                 = Register(CodeInfo{Code: CodePanic, HTTPStatus: http.StatusInternalServerError, GRPCCode: GRPCInternal, Messages: en("Something went wrong, please try again later.")})
+)
+
+// DefaultLanguage is used when no message exists for the requested language
+const DefaultLanguage = "en"
+
+// CodeInfo documents a code
+type CodeInfo struct {
+	Code       Code
+	HTTPStatus int
+	GRPCCode   GRPCCode
+	// Messages are user facing messages keyed by language tag, e.g. "en" or "de-CH"
+	Messages map[string]string
+}
+
+var (
+	catalogMu sync.RWMutex
+	catalog   = make(map[Code]CodeInfo)
+)
+
+func en(msg string) map[string]string {
+	return map[string]string{DefaultLanguage: msg}
+}
+
+// Register adds or replaces a code in the catalog and returns its sentinel error.
+// Applications may register their own codes.
+func Register(info CodeInfo) *CoreError {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	msgs := make(map[string]string, len(info.Messages))
+	for lang, m := range info.Messages {
+		msgs[strings.ToLower(lang)] = m
+	}
+	info.Messages = msgs
+	catalog[info.Code] = info
+	return &CoreError{code: info.Code, msg: string(info.Code)}
+}
+
+// SetUserMessage adds or replaces the user facing message of a code for a language
+func SetUserMessage(code Code, lang, msg string) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	info, ok := catalog[code]
+	if !ok {
+		info = CodeInfo{Code: code}
+	}
+	msgs := make(map[string]string, len(info.Messages)+1)
+	for l, m := range info.Messages {
+		msgs[l] = m
+	}
+	msgs[strings.ToLower(lang)] = msg
+	info.Messages = msgs
+	catalog[code] = info
+}
+
+// Lookup returns the catalog entry of a code
+func Lookup(code Code) (CodeInfo, bool) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	info, ok := catalog[code]
+	return info, ok
+}
+
+// Catalog returns all registered codes sorted by code
+func Catalog() []CodeInfo {
+	catalogMu.RLock()
+	out := make([]CodeInfo, 0, len(catalog))
+	for _, info := range catalog {
+		out = append(out, info)
+	}
+	catalogMu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Code < out[j].Code })
+	return out
+}
+
+// HTTPStatus returns the HTTP status for err, 500 if err has no registered code
+func HTTPStatus(err error) int {
+	if info, ok := Lookup(CodeOf(err)); ok && info.HTTPStatus != 0 {
+		return info.HTTPStatus
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCStatus returns the gRPC code for err, Unknown if err has no registered code
+func GRPCStatus(err error) GRPCCode {
+	if err == nil {
+		return GRPCOK
+	}
+	if info, ok := Lookup(CodeOf(err)); ok {
+		return info.GRPCCode
+	}
+	return GRPCUnknown
+}
+
+// UserMessage returns the message for err to show to users in lang, e.g. "de-CH".
+// It falls back to the base language, then DefaultLanguage, then a generic message.
+func UserMessage(err error, lang string) string {
+	info, _ := Lookup(CodeOf(err))
+	lang = strings.ToLower(lang)
+	candidates := []string{lang}
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		candidates = append(candidates, lang[:i])
+	}
+	candidates = append(candidates, DefaultLanguage)
+	for _, l := range candidates {
+		if m, ok := info.Messages[l]; ok {
+			return m
+		}
+	}
+	return "Something went wrong, please try again later."
+}
+
+// IsCode reports whether any error in the chain of err has code
+func IsCode(err error, code Code) bool {
+	return errors.Is(err, &CoreError{code: code})
+}
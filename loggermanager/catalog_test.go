@@ -0,0 +1,53 @@
+package loggermanager
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestCatalogSentinels(t *testing.T) {
+	err := fmt.Errorf("init: %w", New(CodeSessionNotFound, "Session not found for instance: host1"))
+
+	if !errors.Is(err, ErrSessionNotFound) {
+		t.Error("errors.Is does not match the sentinel")
+	}
+	if errors.Is(err, ErrNoHostFound) {
+		t.Error("errors.Is matched a different sentinel")
+	}
+	if !IsCode(err, CodeSessionNotFound) {
+		t.Error("IsCode failed")
+	}
+	if HTTPStatus(err) != http.StatusServiceUnavailable || GRPCStatus(err) != GRPCUnavailable {
+		t.Errorf("mapping = %d/%d", HTTPStatus(err), GRPCStatus(err))
+	}
+	if HTTPStatus(io.EOF) != http.StatusInternalServerError || GRPCStatus(io.EOF) != GRPCUnknown || GRPCStatus(nil) != GRPCOK {
+		t.Error("unknown errors are not mapped to defaults")
+	}
+}
+
+func TestCatalogCoversEveryCode(t *testing.T) {
+	for _, info := range Catalog() {
+		if info.HTTPStatus == 0 || info.GRPCCode == GRPCOK || info.Messages[DefaultLanguage] == "" {
+			t.Errorf("incomplete catalog entry %+v", info)
+		}
+	}
+}
+
+func TestUserMessage(t *testing.T) {
+	code := Code("TEST_USER_MESSAGE")
+	Register(CodeInfo{Code: code, HTTPStatus: http.StatusTeapot, GRPCCode: GRPCUnknown, Messages: map[string]string{"en": "hello", "DE": "hallo"}})
+	SetUserMessage(code, "de-CH", "grüezi")
+	err := New(code, "internal detail")
+
+	for lang, want := range map[string]string{"de-CH": "grüezi", "de-AT": "hallo", "fr": "hello", "": "hello"} {
+		if got := UserMessage(err, lang); got != want {
+			t.Errorf("UserMessage(%q) = %q, want %q", lang, got, want)
+		}
+	}
+	if got := UserMessage(io.EOF, "en"); got == "" || got == io.EOF.Error() {
+		t.Errorf("UserMessage leaks internals: %q", got)
+	}
+}
@@ -0,0 +1,285 @@
+package loggermanager
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes the complete logger setup. Files are read with a YAML
+// parser, so JSON works as well and durations may be written as "10s".
+type Config struct {
+	Level   string            `json:"level" yaml:"level"`     // root level, default info
+	Modules map[string]string `json:"modules" yaml:"modules"` // level per module, e.g. mongodb: debug
+
+	// Sinks default to a single console sink on stderr
+	Sinks    []SinkConfig    `json:"sinks" yaml:"sinks"`
+	Sampling *SamplingConfig `json:"sampling" yaml:"sampling"`
+	// RedactKeys replaces DefaultRedactedKeys when set
+	RedactKeys []string `json:"redactKeys" yaml:"redactKeys"`
+
+	// Service, Version and Environment are added to every entry along with InitialFields
+	Service       string                 `json:"service" yaml:"service"`
+	Version       string                 `json:"version" yaml:"version"`
+	Environment   string                 `json:"environment" yaml:"environment"`
+	InitialFields map[string]interface{} `json:"initialFields" yaml:"initialFields"`
+
+	DisableCaller bool `json:"disableCaller" yaml:"disableCaller"`
+}
+
+// Environment variables read by ApplyEnv
+const (
+	EnvLogLevel    = "CORELIB_LOG_LEVEL"    // root level
+	EnvLogModules  = "CORELIB_LOG_MODULES"  // module levels, e.g. "mongodb=debug,cachemanager=warn"
+	EnvLogEncoding = "CORELIB_LOG_ENCODING" // encoding of every sink
+	EnvLogFile     = "CORELIB_LOG_FILE"     // filename of file sinks, added as a sink if there is none
+	EnvService     = "CORELIB_SERVICE"
+	EnvVersion     = "CORELIB_VERSION"
+	EnvEnvironment = "CORELIB_ENV"
+)
+
+// LoadConfig reads a JSON or YAML file and validates it, unknown keys are errors.
+// Environment overrides are applied by InitFromConfig.
+func LoadConfig(fileName string) (*Config, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	cfg := new(Config)
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && err != io.EOF {
+		return nil, Wrapf(err, CodeInvalidConfig, "invalid logger config "+fileName)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ApplyEnv overrides cfg with the CORELIB_* environment variables which are set.
+// Modules and Sinks are copied before they are changed.
+func (cfg *Config) ApplyEnv() {
+	if v := os.Getenv(EnvLogLevel); v != "" {
+		cfg.Level = v
+	}
+	if v := os.Getenv(EnvLogModules); v != "" {
+		modules := make(map[string]string, len(cfg.Modules))
+		for m, l := range cfg.Modules {
+			modules[m] = l
+		}
+		cfg.Modules = modules
+		for _, pair := range strings.Split(v, ",") {
+			kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(kv) == 2 {
+				cfg.Modules[kv[0]] = kv[1]
+			}
+		}
+	}
+	if os.Getenv(EnvLogFile) != "" || os.Getenv(EnvLogEncoding) != "" {
+		cfg.Sinks = append([]SinkConfig(nil), cfg.Sinks...)
+	}
+	if v := os.Getenv(EnvLogFile); v != "" {
+		found := false
+		for i := range cfg.Sinks {
+			if cfg.Sinks[i].Type == SinkFile {
+				cfg.Sinks[i].Filename = v
+				found = true
+			}
+		}
+		if !found {
+			cfg.Sinks = append(cfg.Sinks, SinkConfig{Type: SinkFile, Filename: v})
+		}
+	}
+	if v := os.Getenv(EnvLogEncoding); v != "" {
+		for i := range cfg.Sinks {
+			cfg.Sinks[i].Encoding = v
+		}
+	}
+	if v := os.Getenv(EnvService); v != "" {
+		cfg.Service = v
+	}
+	if v := os.Getenv(EnvVersion); v != "" {
+		cfg.Version = v
+	}
+	if v := os.Getenv(EnvEnvironment); v != "" {
+		cfg.Environment = v
+	}
+}
+
+// Validate reports every problem of cfg in one error
+func (cfg *Config) Validate() error {
+	var problems []string
+	add := func(s string) {
+		problems = append(problems, s)
+	}
+	if cfg.Level != "" {
+		if _, err := parseLevel(cfg.Level); err != nil {
+			add("level: " + err.Error())
+		}
+	}
+	for m, l := range cfg.Modules {
+		if _, err := parseLevel(l); err != nil {
+			add("modules." + m + ": " + err.Error())
+		}
+	}
+	for i, s := range cfg.Sinks {
+		if err := s.validate(); err != nil {
+			add("sinks[" + strconv.Itoa(i) + "]: " + err.Error())
+		}
+	}
+	if cfg.Sampling != nil {
+		if err := cfg.Sampling.validate(); err != nil {
+			add("sampling: " + err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return New(CodeInvalidConfig, "invalid logger config: "+strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (s SinkConfig) validate() error {
+	switch s.Type {
+	case SinkStdout, SinkStderr, SinkSyslog:
+	case SinkFile:
+		if s.Filename == "" {
+			return New(CodeInvalidConfig, "file sink requires a filename")
+		}
+	case SinkHTTP, SinkOTLP:
+		if s.URL == "" {
+			return New(CodeInvalidConfig, s.Type+" sink requires a url")
+		}
+	default:
+		return New(CodeInvalidConfig, "unknown sink type: "+s.Type)
+	}
+	if s.Level != "" {
+		if _, err := parseLevel(s.Level); err != nil {
+			return err
+		}
+	}
+	switch s.Encoding {
+	case "", EncodingJSON, EncodingConsole, EncodingOTel:
+	default:
+		return New(CodeInvalidConfig, "unknown encoding: "+s.Encoding)
+	}
+	switch s.Overflow {
+	case "", OverflowDrop, OverflowBlock:
+	default:
+		return New(CodeInvalidConfig, "unknown overflow policy: "+s.Overflow)
+	}
+	switch s.Rotate {
+	case "", RotateDaily, RotateHourly:
+	default:
+		return New(CodeInvalidConfig, "unknown rotation: "+s.Rotate)
+	}
+	if s.MaxTotalSize > 0 && s.Rotate == "" && s.MaxSize == 0 {
+		return New(CodeInvalidConfig, "maxTotalSize requires rotate or maxSize, the file is never rotated otherwise")
+	}
+	if s.MaxSize < 0 || s.MaxBackups < 0 || s.MaxAge < 0 || s.MaxTotalSize < 0 || s.BatchSize < 0 || s.BufferSize < 0 {
+		return New(CodeInvalidConfig, "sizes and counts must not be negative")
+	}
+	return nil
+}
+
+func (s *SamplingConfig) validate() error {
+	policies := make([]SamplingPolicy, 0, len(s.Levels)+len(s.Modules)+1)
+	if s.Default != nil {
+		policies = append(policies, *s.Default)
+	}
+	for _, p := range s.Levels {
+		policies = append(policies, p)
+	}
+	for _, p := range s.Modules {
+		policies = append(policies, p)
+	}
+	for _, p := range policies {
+		if p.Initial < 0 || p.Thereafter < 0 || p.Tick < 0 {
+			return New(CodeInvalidConfig, "policy values must not be negative")
+		}
+	}
+	return nil
+}
+
+func parseLevel(s string) (zapcore.Level, error) {
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(s)); err != nil {
+		return lvl, New(CodeInvalidConfig, "unknown level: "+s)
+	}
+	return lvl, nil
+}
+
+// InitFromFile initializes the package logger from a JSON or YAML config file
+func InitFromFile(fileName string) error {
+	cfg, err := LoadConfig(fileName)
+	if err != nil {
+		return err
+	}
+	return InitFromConfig(*cfg)
+}
+
+// InitFromConfig applies the environment overrides to cfg, validates it and replaces the package logger accordingly.
+// Module levels set before are removed, redaction and sampling are kept unless cfg sets them.
+func InitFromConfig(cfg Config) error {
+	cfg.ApplyEnv()
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	sinks := cfg.Sinks
+	if len(sinks) == 0 {
+		sinks = []SinkConfig{{Type: SinkStderr, Encoding: EncodingConsole}}
+	}
+	cores, writers, err := buildSinks(sinks)
+	if err != nil {
+		return err
+	}
+
+	root := zapcore.InfoLevel
+	if cfg.Level != "" {
+		root, _ = parseLevel(cfg.Level)
+	}
+	levels.resetAll()
+	SetLevel("", root)
+	for m, l := range cfg.Modules {
+		lvl, _ := parseLevel(l)
+		SetLevel(m, lvl)
+	}
+	if cfg.RedactKeys != nil {
+		SetRedactedKeys(cfg.RedactKeys...)
+	}
+	if cfg.Sampling != nil {
+		SetSampling(cfg.Sampling)
+	}
+	setProduction(cfg.Environment)
+
+	opts := []zap.Option{zap.Fields(cfg.fields()...)}
+	if !cfg.DisableCaller {
+		opts = append(opts, zap.AddCaller())
+	}
+	SetLogger(NewZapLogger(zap.New(wrapCore(cores...), opts...)))
+	replaceSinks(writers)
+	return nil
+}
+
+func (cfg *Config) fields() []zap.Field {
+	var fields []zap.Field
+	for _, f := range []struct{ key, val string }{
+		{"service", cfg.Service},
+		{"version", cfg.Version},
+		{"environment", cfg.Environment},
+	} {
+		if f.val != "" {
+			fields = append(fields, zap.String(f.key, f.val))
+		}
+	}
+	for k, v := range cfg.InitialFields {
+		fields = append(fields, zap.Any(k, v))
+	}
+	return fields
+}
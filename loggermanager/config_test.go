@@ -0,0 +1,151 @@
+package loggermanager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "logger.yaml")
+	os.WriteFile(fileName, []byte(`
+level: warn
+modules:
+  mongodb: debug
+service: orders
+sinks:
+  - type: file
+    filename: `+filepath.Join(dir, "app.log")+`
+    level: info
+  - type: http
+    url: http://localhost:4318
+    flushInterval: 2s
+sampling:
+  levels:
+    error: {initial: 10, thereafter: 100, tick: 1s}
+`), 0644)
+
+	t.Setenv(EnvLogModules, "cachemanager=error")
+	t.Setenv(EnvVersion, "1.2.3")
+
+	cfg, err := LoadConfig(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Version != "" || len(cfg.Modules) != 1 {
+		t.Errorf("LoadConfig applied the environment: %+v", cfg)
+	}
+	modules := cfg.Modules
+	cfg.ApplyEnv()
+	if len(modules) != 1 {
+		t.Error("ApplyEnv changed the modules of the loaded config")
+	}
+	if cfg.Level != "warn" || cfg.Service != "orders" || cfg.Version != "1.2.3" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if cfg.Modules["mongodb"] != "debug" || cfg.Modules["cachemanager"] != "error" {
+		t.Errorf("unexpected modules %v", cfg.Modules)
+	}
+	if len(cfg.Sinks) != 2 || cfg.Sinks[1].FlushInterval != 2*time.Second {
+		t.Errorf("unexpected sinks %+v", cfg.Sinks)
+	}
+	if p := cfg.Sampling.Levels[zapcore.ErrorLevel]; p.Thereafter != 100 || p.Tick != time.Second {
+		t.Errorf("unexpected sampling %+v", cfg.Sampling)
+	}
+}
+
+func TestLoadConfigUnknownKeys(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "logger.yaml")
+	os.WriteFile(fileName, []byte("level: warn\nsinks:\n  - type: stdout\n    levle: info\n"), 0644)
+	if _, err := LoadConfig(fileName); err == nil || !strings.Contains(err.Error(), "levle") {
+		t.Errorf("LoadConfig error = %v, want the unknown key", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := Config{
+		Level:   "loud",
+		Modules: map[string]string{"mongodb": "quiet"},
+		Sinks:   []SinkConfig{{Type: "kafka"}, {Type: SinkFile}, {Type: SinkStdout, Overflow: "spill"}, {Type: SinkFile, Filename: "app.log", Rotate: "weekly"}, {Type: SinkFile, Filename: "app.log", MaxTotalSize: 10}},
+	}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{"level: unknown level: loud", "modules.mongodb", "sinks[0]", "sinks[1]", "sinks[2]", "sinks[3]: unknown rotation", "sinks[4]: maxTotalSize requires rotate"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestInitFromConfig(t *testing.T) {
+	prev := GetLogger()
+	defer SetLogger(prev)
+	defer Close()
+	defer SetLevel("", zapcore.DebugLevel)
+
+	fileName := filepath.Join(t.TempDir(), "app.log")
+	err := InitFromConfig(Config{
+		Level:   "info",
+		Service: "orders",
+		Sinks:   []SinkConfig{{Type: SinkFile, Filename: fileName}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	LogDebug("hidden")
+	LogInfo("visible")
+	Sync()
+
+	b, _ := os.ReadFile(fileName)
+	out := string(b)
+	if strings.Contains(out, "hidden") || !strings.Contains(out, `"msg":"visible"`) || !strings.Contains(out, `"service":"orders"`) {
+		t.Errorf("unexpected log file contents %s", out)
+	}
+}
+
+func TestInitFromConfigReplacesState(t *testing.T) {
+	prev := GetLogger()
+	defer SetLogger(prev)
+	defer Close()
+	defer SetLevel("", zapcore.DebugLevel)
+	defer SetSampling(nil)
+
+	SetLevel("mongodb", zapcore.ErrorLevel)
+	SetSampling(&SamplingConfig{Default: &SamplingPolicy{Initial: 1, Thereafter: 10, Tick: time.Second}})
+	t.Setenv(EnvLogModules, "cachemanager=warn")
+	if err := InitFromConfig(Config{Sinks: []SinkConfig{{Type: SinkStderr}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, modules := Levels(); len(modules) != 1 || modules["cachemanager"] != zapcore.WarnLevel {
+		t.Errorf("module levels = %v, want only the environment override", modules)
+	}
+	samplerMu.Lock()
+	kept := curSample != nil
+	samplerMu.Unlock()
+	if !kept {
+		t.Error("InitFromConfig removed the sampling set before")
+	}
+}
+
+func TestInitLegacyKeys(t *testing.T) {
+	prev := GetLogger()
+	defer SetLogger(prev)
+	defer Close()
+	defer SetLevel("", zapcore.DebugLevel)
+
+	fileName := filepath.Join(t.TempDir(), "app.log")
+	Init(fileName, 1, 1, 1, zapcore.InfoLevel)
+	LogInfo("visible")
+	Sync()
+	b, _ := os.ReadFile(fileName)
+	if !strings.Contains(string(b), `"M":"visible"`) || !strings.Contains(string(b), `"L":"INFO"`) {
+		t.Errorf("unexpected log file contents %s", b)
+	}
+}
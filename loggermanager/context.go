@@ -0,0 +1,164 @@
+package loggermanager
+
+import (
+	"context"
+	"sync"
+)
+
+type ctxKey int
+
+const (
+	loggerKey ctxKey = iota
+	requestIDKey
+	traceIDKey
+	spanIDKey
+	userKey
+	tenantKey
+)
+
+// Field names used for values correlated from a context
+const (
+	FieldRequestID = "request_id"
+	FieldTraceID   = "trace_id"
+	FieldSpanID    = "span_id"
+	FieldUser      = "user"
+	FieldTenant    = "tenant"
+)
+
+// ContextWithLogger returns a copy of ctx carrying l
+func ContextWithLogger(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// ContextWithRequestID returns a copy of ctx carrying the request id
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// ContextWithTrace returns a copy of ctx carrying trace and span ids
+func ContextWithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	ctx = context.WithValue(ctx, traceIDKey, traceID)
+	return context.WithValue(ctx, spanIDKey, spanID)
+}
+
+// ContextWithUser returns a copy of ctx carrying the user
+func ContextWithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey, user)
+}
+
+// ContextWithTenant returns a copy of ctx carrying the tenant
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+// RequestIDFromContext returns the request id stored in ctx, if any
+func RequestIDFromContext(ctx context.Context) string {
+	return ctxString(ctx, requestIDKey)
+}
+
+// TraceFromContext returns the trace and span ids stored in ctx, if any
+func TraceFromContext(ctx context.Context) (traceID, spanID string) {
+	return ctxString(ctx, traceIDKey), ctxString(ctx, spanIDKey)
+}
+
+// UserFromContext returns the user stored in ctx, if any
+func UserFromContext(ctx context.Context) string {
+	return ctxString(ctx, userKey)
+}
+
+// TenantFromContext returns the tenant stored in ctx, if any
+func TenantFromContext(ctx context.Context) string {
+	return ctxString(ctx, tenantKey)
+}
+
+func ctxString(ctx context.Context, key ctxKey) string {
+	if ctx == nil {
+		return ""
+	}
+	s, _ := ctx.Value(key).(string)
+	return s
+}
+
+// ContextFields returns the correlation values stored in ctx as key/value pairs
+func ContextFields(ctx context.Context) []interface{} {
+	if ctx == nil {
+		return nil
+	}
+	var kv []interface{}
+	for _, f := range []struct {
+		name string
+		key  ctxKey
+	}{
+		{FieldRequestID, requestIDKey},
+		{FieldTraceID, traceIDKey},
+		{FieldSpanID, spanIDKey},
+		{FieldUser, userKey},
+		{FieldTenant, tenantKey},
+	} {
+		if v := ctxString(ctx, f.key); v != "" {
+			kv = append(kv, f.name, v)
+		}
+	}
+	return kv
+}
+
+// FromContext returns the logger carried by ctx, or the package logger, with the
+// correlation values of ctx attached.
+func FromContext(ctx context.Context) Logger {
+	var l Logger
+	if ctx != nil {
+		l, _ = ctx.Value(loggerKey).(Logger)
+	}
+	kv := ContextFields(ctx)
+	if l == nil {
+		return With(kv...)
+	}
+	if len(kv) == 0 {
+		return l
+	}
+	return l.With(kv...)
+}
+
+var namedLoggers sync.Map // name -> Logger of ContextLogger
+
+// ContextLogger returns the logger of a module for ctx, e.g. ContextLogger(ctx, "cachemanager"): the logger
+// carried by ctx named name, or the package logger named name, with the correlation values of ctx attached.
+// Fields are attached to a logger kept per name, which is returned as is for contexts without any.
+func ContextLogger(ctx context.Context, name string) Logger {
+	var l Logger
+	if ctx != nil {
+		l, _ = ctx.Value(loggerKey).(Logger)
+	}
+	if l != nil {
+		l = l.Named(name)
+	} else if cached, ok := namedLoggers.Load(name); ok {
+		l = cached.(Logger)
+	} else {
+		cached, _ = namedLoggers.LoadOrStore(name, Named(name))
+		l = cached.(Logger)
+	}
+	if kv := ContextFields(ctx); len(kv) > 0 {
+		return l.With(kv...)
+	}
+	return l
+}
+
+// LogDebugCtx logs a message at level Debug with the correlation values of ctx.
+func LogDebugCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	skipCaller(FromContext(ctx), 1).Debug(msg, keysAndValues...)
+}
+
+// LogInfoCtx logs a message at level Info with the correlation values of ctx.
+func LogInfoCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	skipCaller(FromContext(ctx), 1).Info(msg, keysAndValues...)
+}
+
+// LogWarnCtx logs a message at level Warn with the correlation values of ctx.
+func LogWarnCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	skipCaller(FromContext(ctx), 1).Warn(msg, keysAndValues...)
+}
+
+// LogErrorCtx logs a message at level Error with the correlation values of ctx.
+func LogErrorCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	skipCaller(FromContext(ctx), 1).Error(msg, keysAndValues...)
+}
@@ -0,0 +1,92 @@
+package loggermanager
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func observe(t *testing.T) *observer.ObservedLogs {
+	prev := GetLogger()
+	core, logs := observer.New(zapcore.DebugLevel)
+	SetLogger(NewZapLogger(zap.New(core)))
+	t.Cleanup(func() { SetLogger(prev) })
+	return logs
+}
+
+func TestLogErrorCtx(t *testing.T) {
+	logs := observe(t)
+
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	ctx = ContextWithTrace(ctx, "trace-1", "span-1")
+	ctx = ContextWithTenant(ctx, "acme")
+	LogErrorCtx(ctx, "failed", "key", "k1")
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	got := entries[0].ContextMap()
+	want := map[string]interface{}{
+		FieldRequestID: "req-1",
+		FieldTraceID:   "trace-1",
+		FieldSpanID:    "span-1",
+		FieldTenant:    "acme",
+		"key":          "k1",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("field %s = %v, want %v", k, got[k], v)
+		}
+	}
+	if _, ok := got[FieldUser]; ok {
+		t.Errorf("unexpected %s field", FieldUser)
+	}
+}
+
+func TestFromContextLogger(t *testing.T) {
+	logs := observe(t)
+
+	ctx := ContextWithLogger(context.Background(), GetLogger().With("component", "api"))
+	ctx = ContextWithUser(ctx, "alice")
+	FromContext(ctx).Named("cachemanager").Info("hit")
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if entries[0].LoggerName != "cachemanager" {
+		t.Errorf("logger name = %q, want cachemanager", entries[0].LoggerName)
+	}
+	fields := entries[0].ContextMap()
+	if fields["component"] != "api" || fields[FieldUser] != "alice" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+}
+
+func TestContextLogger(t *testing.T) {
+	logs := observe(t)
+
+	if ContextLogger(nil, "mongodb") != ContextLogger(context.Background(), "mongodb") {
+		t.Error("logger without correlation values not reused")
+	}
+	ContextLogger(ContextWithRequestID(context.Background(), "req-9"), "mongodb").Warn("slow")
+	ctx := ContextWithLogger(context.Background(), GetLogger().With("component", "api"))
+	ContextLogger(ctx, "mongodb").Info("carried")
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	for _, e := range entries {
+		if e.LoggerName != "mongodb" {
+			t.Errorf("%q logged by %q", e.Message, e.LoggerName)
+		}
+	}
+	if entries[0].ContextMap()[FieldRequestID] != "req-9" || entries[1].ContextMap()["component"] != "api" {
+		t.Errorf("unexpected fields %v, %v", entries[0].ContextMap(), entries[1].ContextMap())
+	}
+}
@@ -1,18 +1,215 @@
 package loggermanager
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Code identifies a kind of failure. Codes are stable, messages are not.
+type Code string
+
+// CodeUnknown is the code of errors created without one
+const CodeUnknown Code = ""
+
+// Severity tells how bad an error is
+type Severity int
+
+// Severities, errors are SeverityError unless set otherwise
+const (
+	SeverityInfo Severity = iota + 1
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	}
+	return "error"
+}
+
+const maxStackDepth = 32
 
 // CoreError custom error
 type CoreError struct {
-	msg string
+	code     Code
+	msg      string
+	cause    error
+	severity Severity
+	details  map[string]interface{}
+	stack    []uintptr
 }
 
-// Wrap error
+// Wrap error without a code, prefer New or Wrapf
 func Wrap(msg string) *CoreError {
-	err := CoreError{}
-	err.msg = msg
-	return &err
+	return newError(CodeUnknown, msg, nil)
+}
+
+// New creates an error with a code
+func New(code Code, msg string) *CoreError {
+	return newError(code, msg, nil)
+}
+
+// Newf creates an error with a code and a formatted message
+func Newf(code Code, format string, args ...interface{}) *CoreError {
+	return newError(code, fmt.Sprintf(format, args...), nil)
+}
+
+// Wrapf creates an error with a code and a formatted message caused by err
+func Wrapf(err error, code Code, format string, args ...interface{}) *CoreError {
+	return newError(code, fmt.Sprintf(format, args...), err)
 }
+
+// newError must be called directly by an exported constructor for the stack to start at its caller
+func newError(code Code, msg string, cause error) *CoreError {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(3, pcs)
+	return &CoreError{code: code, msg: msg, cause: cause, stack: pcs[:n]}
+}
+
 func (cerr *CoreError) Error() string {
-	return fmt.Sprintf(cerr.msg)
+	if cerr.cause == nil {
+		return cerr.msg
+	}
+	if cerr.msg == "" {
+		return cerr.cause.Error()
+	}
+	return cerr.msg + ": " + cerr.cause.Error()
+}
+
+// Code returns the code of the error
+func (cerr *CoreError) Code() Code {
+	return cerr.code
+}
+
+// Message returns the message without the cause
+func (cerr *CoreError) Message() string {
+	return cerr.msg
+}
+
+// Severity returns the severity, SeverityError unless set with WithSeverity
+func (cerr *CoreError) Severity() Severity {
+	if cerr.severity == 0 {
+		return SeverityError
+	}
+	return cerr.severity
+}
+
+// Unwrap returns the cause for errors.Is and errors.As
+func (cerr *CoreError) Unwrap() error {
+	return cerr.cause
+}
+
+// Is matches errors with the same code, so errors.Is(err, New(code, "")) checks the code
+func (cerr *CoreError) Is(target error) bool {
+	t, ok := target.(*CoreError)
+	if !ok {
+		return false
+	}
+	if t.code == CodeUnknown {
+		return t == cerr
+	}
+	return t.code == cerr.code
+}
+
+// WithDetail adds a key/value detail and returns the error
+func (cerr *CoreError) WithDetail(key string, val interface{}) *CoreError {
+	if cerr.details == nil {
+		cerr.details = make(map[string]interface{})
+	}
+	cerr.details[key] = val
+	return cerr
+}
+
+// WithSeverity sets the severity and returns the error
+func (cerr *CoreError) WithSeverity(s Severity) *CoreError {
+	cerr.severity = s
+	return cerr
+}
+
+// Details returns a copy of the details
+func (cerr *CoreError) Details() map[string]interface{} {
+	d := make(map[string]interface{}, len(cerr.details))
+	for k, v := range cerr.details {
+		d[k] = v
+	}
+	return d
+}
+
+// StackTrace returns the frames captured when the error was created
+func (cerr *CoreError) StackTrace() []runtime.Frame {
+	frames := runtime.CallersFrames(cerr.stack)
+	var out []runtime.Frame
+	for {
+		f, more := frames.Next()
+		out = append(out, f)
+		if !more {
+			return out
+		}
+	}
+}
+
+// Format supports %+v which adds the code, details and stack trace
+func (cerr *CoreError) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		io.WriteString(s, cerr.Error())
+		if cerr.code != CodeUnknown {
+			fmt.Fprintf(s, "\ncode: %s", cerr.code)
+		}
+		for _, k := range cerr.detailKeys() {
+			fmt.Fprintf(s, "\n%s: %v", k, cerr.details[k])
+		}
+		for _, f := range cerr.StackTrace() {
+			fmt.Fprintf(s, "\n%s\n\t%s:%d", f.Function, f.File, f.Line)
+		}
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", cerr.Error())
+	default:
+		io.WriteString(s, cerr.Error())
+	}
+}
+
+func (cerr *CoreError) detailKeys() []string {
+	keys := make([]string, 0, len(cerr.details))
+	for k := range cerr.details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// MarshalLogObject logs the error as an object when passed to Any
+func (cerr *CoreError) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("message", cerr.Error())
+	if cerr.code != CodeUnknown {
+		enc.AddString("code", string(cerr.code))
+	}
+	enc.AddString("severity", cerr.Severity().String())
+	for _, k := range cerr.detailKeys() {
+		if err := enc.AddReflected(k, cerr.details[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CodeOf returns the code of the first CoreError in the chain of err
+func CodeOf(err error) Code {
+	var cerr *CoreError
+	if errors.As(err, &cerr) {
+		return cerr.code
+	}
+	return CodeUnknown
 }
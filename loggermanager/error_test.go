@@ -0,0 +1,50 @@
+package loggermanager
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCoreErrorWrapf(t *testing.T) {
+	err := Wrapf(io.EOF, "CACHE_FILE_DECODE_FAILED", "decoding %s", "cache.json").WithDetail("file", "cache.json")
+
+	if got := err.Error(); got != "decoding cache.json: EOF" {
+		t.Errorf("Error() = %q", got)
+	}
+	if !errors.Is(err, io.EOF) {
+		t.Error("errors.Is does not reach the cause")
+	}
+	if !errors.Is(fmt.Errorf("load: %w", err), New("CACHE_FILE_DECODE_FAILED", "")) {
+		t.Error("errors.Is does not match by code")
+	}
+	if errors.Is(err, New("CACHE_FILE_READ_FAILED", "")) {
+		t.Error("errors.Is matched a different code")
+	}
+	var cerr *CoreError
+	if !errors.As(fmt.Errorf("load: %w", err), &cerr) || cerr.Code() != "CACHE_FILE_DECODE_FAILED" {
+		t.Error("errors.As failed")
+	}
+	if CodeOf(err) != "CACHE_FILE_DECODE_FAILED" || CodeOf(io.EOF) != CodeUnknown {
+		t.Error("CodeOf returned the wrong code")
+	}
+	if err.Severity() != SeverityError {
+		t.Errorf("default severity = %v", err.Severity())
+	}
+}
+
+func TestCoreErrorFormat(t *testing.T) {
+	err := New("NO_HOST_FOUND", "no host %s").WithDetail("host", "primary")
+
+	if got := fmt.Sprintf("%v", err); got != "no host %s" {
+		t.Errorf("%%v = %q, message must not be used as a format", got)
+	}
+	verbose := fmt.Sprintf("%+v", err)
+	for _, want := range []string{"code: NO_HOST_FOUND", "host: primary", "TestCoreErrorFormat"} {
+		if !strings.Contains(verbose, want) {
+			t.Errorf("%%+v missing %q:\n%s", want, verbose)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package loggermanager
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Field is a typed key/value pair. Fields can be mixed freely with loose
+// key/value pairs in any Logger method.
+type Field = zap.Field
+
+// String constructs a field with a string value
+func String(key, val string) Field {
+	return zap.String(key, val)
+}
+
+// Strings constructs a field with a string slice value
+func Strings(key string, val []string) Field {
+	return zap.Strings(key, val)
+}
+
+// Int constructs a field with an int value
+func Int(key string, val int) Field {
+	return zap.Int(key, val)
+}
+
+// Int64 constructs a field with an int64 value
+func Int64(key string, val int64) Field {
+	return zap.Int64(key, val)
+}
+
+// Float64 constructs a field with a float64 value
+func Float64(key string, val float64) Field {
+	return zap.Float64(key, val)
+}
+
+// Bool constructs a field with a bool value
+func Bool(key string, val bool) Field {
+	return zap.Bool(key, val)
+}
+
+// Duration constructs a field with a time.Duration value
+func Duration(key string, val time.Duration) Field {
+	return zap.Duration(key, val)
+}
+
+// Time constructs a field with a time.Time value
+func Time(key string, val time.Time) Field {
+	return zap.Time(key, val)
+}
+
+// Err constructs a field with the key "error"
+func Err(err error) Field {
+	return zap.Error(err)
+}
+
+// Any constructs a field choosing the best encoding for val
+func Any(key string, val interface{}) Field {
+	return zap.Any(key, val)
+}
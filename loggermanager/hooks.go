@@ -0,0 +1,96 @@
+package loggermanager
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	hooksMu sync.Mutex
+	hooks   atomic.Value // []zapcore.Core
+)
+
+// AddCore makes core receive every entry written through the package logger, after levels,
+// sampling and redaction apply. It survives logger changes until remove is called.
+func AddCore(core zapcore.Core) (remove func()) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	prev := loadHooks()
+	next := make([]zapcore.Core, len(prev), len(prev)+1)
+	copy(next, prev)
+	hooks.Store(append(next, core))
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			hooksMu.Lock()
+			defer hooksMu.Unlock()
+			prev := loadHooks()
+			next := make([]zapcore.Core, 0, len(prev))
+			for _, c := range prev {
+				if c != core {
+					next = append(next, c)
+				}
+			}
+			hooks.Store(next)
+		})
+	}
+}
+
+func loadHooks() []zapcore.Core {
+	h, _ := hooks.Load().([]zapcore.Core)
+	return h
+}
+
+// hookCore forwards entries to the cores added with AddCore
+type hookCore struct {
+	fields []zapcore.Field
+}
+
+func (c *hookCore) Enabled(lvl zapcore.Level) bool {
+	for _, h := range loadHooks() {
+		if h.Enabled(lvl) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	return &hookCore{fields: append(append(all, c.fields...), fields...)}
+}
+
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *hookCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if len(c.fields) > 0 {
+		fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	}
+	var err error
+	for _, h := range loadHooks() {
+		if h.Enabled(ent.Level) {
+			if werr := h.Write(ent, fields); werr != nil {
+				err = werr
+			}
+		}
+	}
+	return err
+}
+
+func (c *hookCore) Sync() error {
+	var err error
+	for _, h := range loadHooks() {
+		if serr := h.Sync(); serr != nil {
+			err = serr
+		}
+	}
+	return err
+}
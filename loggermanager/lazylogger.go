@@ -0,0 +1,100 @@
+package loggermanager
+
+import (
+	"strings"
+	"sync"
+)
+
+// lazyLogger resolves against the current package logger on use, so loggers
+// created in package vars follow later calls to Init or SetLogger.
+type lazyLogger struct {
+	name string
+	kv   []interface{}
+	skip int
+
+	mu  sync.Mutex
+	gen uint64
+	l   Logger
+}
+
+// Named returns a logger for a module, e.g. Named("cachemanager").
+// Safe to keep in a package level var.
+func Named(name string) Logger {
+	return &lazyLogger{name: name}
+}
+
+// With returns a logger which adds keysAndValues to every entry.
+// Safe to keep in a package level var.
+func With(keysAndValues ...interface{}) Logger {
+	return &lazyLogger{kv: keysAndValues}
+}
+
+func (ll *lazyLogger) resolve() Logger {
+	mu.RLock()
+	gen, base := generation, std
+	mu.RUnlock()
+
+	ll.mu.Lock()
+	defer ll.mu.Unlock()
+	if ll.l != nil && ll.gen == gen {
+		return ll.l
+	}
+	l := skipCaller(base, 1+ll.skip)
+	if ll.name != "" {
+		l = l.Named(ll.name)
+	}
+	if len(ll.kv) > 0 {
+		l = l.With(ll.kv...)
+	}
+	ll.gen, ll.l = gen, l
+	return l
+}
+
+// Debug -
+func (ll *lazyLogger) Debug(msg string, keysAndValues ...interface{}) {
+	ll.resolve().Debug(msg, keysAndValues...)
+}
+
+// Info -
+func (ll *lazyLogger) Info(msg string, keysAndValues ...interface{}) {
+	ll.resolve().Info(msg, keysAndValues...)
+}
+
+// Warn -
+func (ll *lazyLogger) Warn(msg string, keysAndValues ...interface{}) {
+	ll.resolve().Warn(msg, keysAndValues...)
+}
+
+// Error -
+func (ll *lazyLogger) Error(msg string, keysAndValues ...interface{}) {
+	ll.resolve().Error(msg, keysAndValues...)
+}
+
+// Panic -
+func (ll *lazyLogger) Panic(msg string, keysAndValues ...interface{}) {
+	ll.resolve().Panic(msg, keysAndValues...)
+}
+
+// With -
+func (ll *lazyLogger) With(keysAndValues ...interface{}) Logger {
+	kv := make([]interface{}, 0, len(ll.kv)+len(keysAndValues))
+	kv = append(kv, ll.kv...)
+	return &lazyLogger{name: ll.name, kv: append(kv, keysAndValues...), skip: ll.skip}
+}
+
+// Named -
+func (ll *lazyLogger) Named(name string) Logger {
+	if ll.name != "" {
+		name = strings.Join([]string{ll.name, name}, ".")
+	}
+	return &lazyLogger{name: name, kv: ll.kv, skip: ll.skip}
+}
+
+func (ll *lazyLogger) withCallerSkip(skip int) Logger {
+	return &lazyLogger{name: ll.name, kv: ll.kv, skip: ll.skip + skip}
+}
+
+// Sync -
+func (ll *lazyLogger) Sync() error {
+	return ll.resolve().Sync()
+}
@@ -0,0 +1,92 @@
+package loggermanager
+
+import (
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLazyLoggerFollowsSetLogger(t *testing.T) {
+	l := Named("cachemanager").With("host", "h1")
+	logs := observe(t) // installed after l was created
+
+	l.Info("hit")
+	l.Named("l1").With("key", "k1").Warn("miss")
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if e := entries[0]; e.LoggerName != "cachemanager" || e.ContextMap()["host"] != "h1" {
+		t.Errorf("entry = %q %v", e.LoggerName, e.ContextMap())
+	}
+	if e := entries[1]; e.LoggerName != "cachemanager.l1" || e.ContextMap()["host"] != "h1" || e.ContextMap()["key"] != "k1" {
+		t.Errorf("child entry = %q %v", e.LoggerName, e.ContextMap())
+	}
+}
+
+func TestLazyLoggerWithDoesNotShare(t *testing.T) {
+	logs := observe(t)
+
+	parent := With("a", 1)
+	one, two := parent.With("b", 2), parent.With("c", 3)
+	one.Info("one")
+	two.Info("two")
+	parent.Info("parent")
+
+	want := []map[string]interface{}{{"a": int64(1), "b": int64(2)}, {"a": int64(1), "c": int64(3)}, {"a": int64(1)}}
+	for i, e := range logs.All() {
+		fields := e.ContextMap()
+		if len(fields) != len(want[i]) {
+			t.Errorf("%s: fields = %v, want %v", e.Message, fields, want[i])
+			continue
+		}
+		for k, v := range want[i] {
+			if fields[k] != v {
+				t.Errorf("%s: fields = %v, want %v", e.Message, fields, want[i])
+			}
+		}
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	msg, fields := splitArgs([]interface{}{"saved", String("key", "k1"), 3, apiKey("live-123"), Int("n", 2)})
+	if msg != "saved 3 key-****" {
+		t.Errorf("msg = %q", msg)
+	}
+	if len(fields) != 2 || fields[0].(Field).Key != "key" || fields[1].(Field).Key != "n" {
+		t.Errorf("fields = %v", fields)
+	}
+	if msg, fields := splitArgs(nil); msg != "" || fields != nil {
+		t.Errorf("splitArgs(nil) = %q, %v", msg, fields)
+	}
+}
+
+func TestLogwInvalidPairs(t *testing.T) {
+	logs := observe(t)
+
+	LogInfow("odd", "a", 1, "dangling")
+	LogWarnw("non-string key", 42, "v", "b", 2)
+
+	odd := logs.FilterMessage("odd").All()
+	if len(odd) != 1 || len(odd[0].ContextMap()) != 1 || odd[0].ContextMap()["a"] != int64(1) {
+		t.Errorf("odd entry = %v", odd)
+	}
+	nonString := logs.FilterMessage("non-string key").All()
+	if len(nonString) != 1 || len(nonString[0].ContextMap()) != 1 || nonString[0].ContextMap()["b"] != int64(2) {
+		t.Errorf("non-string key entry = %v", nonString)
+	}
+	// the dropped pairs are reported instead of being lost silently
+	for _, e := range logs.FilterLevelExact(zapcore.ErrorLevel).All() {
+		if _, ok := e.ContextMap()["ignored"]; ok {
+			continue
+		}
+		if _, ok := e.ContextMap()["invalid"]; ok {
+			continue
+		}
+		t.Errorf("unexpected error entry %q %v", e.Message, e.ContextMap())
+	}
+	if n := logs.FilterLevelExact(zapcore.ErrorLevel).Len(); n != 2 {
+		t.Errorf("got %d reports of dropped pairs, want 2", n)
+	}
+}
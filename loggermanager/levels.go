@@ -0,0 +1,336 @@
+package loggermanager
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levelRegistry holds the root level and per-module overrides. A module is
+// the logger name given to Named, nested names fall back to their parents.
+type levelRegistry struct {
+	mu      sync.RWMutex
+	root    zap.AtomicLevel
+	modules map[string]zap.AtomicLevel
+	reverts map[string]*pendingRevert
+	least   zap.AtomicLevel // most verbose level of root and modules, read by every log call
+}
+
+// pendingRevert restores a module to its previous level when a temporary change expires
+type pendingRevert struct {
+	timer *time.Timer // nil if the change lasts until revertAll
+	level zapcore.Level
+	isSet bool // false if the module had no override before the change
+}
+
+var levels = newLevelRegistry(zapcore.DebugLevel)
+
+func newLevelRegistry(root zapcore.Level) *levelRegistry {
+	return &levelRegistry{
+		root:    zap.NewAtomicLevelAt(root),
+		modules: make(map[string]zap.AtomicLevel),
+		reverts: make(map[string]*pendingRevert),
+		least:   zap.NewAtomicLevelAt(root),
+	}
+}
+
+// enabled reports whether a logger named name logs at lvl
+func (r *levelRegistry) enabled(name string, lvl zapcore.Level) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for name != "" {
+		if al, ok := r.modules[name]; ok {
+			return al.Enabled(lvl)
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return r.root.Enabled(lvl)
+}
+
+// min returns the most verbose level any module logs at, without taking r.mu
+func (r *levelRegistry) min() zapcore.Level {
+	return r.least.Level()
+}
+
+// updateMin recomputes the level returned by min, it requires r.mu to be held for writing
+func (r *levelRegistry) updateMin() {
+	m := r.root.Level()
+	for _, al := range r.modules {
+		if l := al.Level(); l < m {
+			m = l
+		}
+	}
+	r.least.SetLevel(m)
+}
+
+func (r *levelRegistry) get(module string) (zapcore.Level, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if module == "" {
+		return r.root.Level(), true
+	}
+	al, ok := r.modules[module]
+	if !ok {
+		return r.root.Level(), false
+	}
+	return al.Level(), true
+}
+
+// set changes module's level. Temporary changes are reverted after revertAfter
+// if it is positive, or else by revertAll.
+func (r *levelRegistry) set(module string, lvl zapcore.Level, temporary bool, revertAfter time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prev, hadPrev := r.root.Level(), true
+	if module != "" {
+		var al zap.AtomicLevel
+		al, hadPrev = r.modules[module]
+		if hadPrev {
+			prev = al.Level()
+		}
+	}
+	if p, ok := r.reverts[module]; ok {
+		// keep the level from before the first temporary change
+		p.stop()
+		prev, hadPrev = p.level, p.isSet
+		delete(r.reverts, module)
+	}
+
+	r.store(module, lvl)
+
+	if temporary {
+		p := &pendingRevert{level: prev, isSet: hadPrev}
+		if revertAfter > 0 {
+			p.timer = time.AfterFunc(revertAfter, func() { r.revert(module, p) })
+		}
+		r.reverts[module] = p
+	}
+}
+
+func (p *pendingRevert) stop() {
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+}
+
+// store requires r.mu to be held for writing
+func (r *levelRegistry) store(module string, lvl zapcore.Level) {
+	defer r.updateMin()
+	if module == "" {
+		r.root.SetLevel(lvl)
+		return
+	}
+	if al, ok := r.modules[module]; ok {
+		al.SetLevel(lvl)
+		return
+	}
+	r.modules[module] = zap.NewAtomicLevelAt(lvl)
+}
+
+func (r *levelRegistry) reset(module string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.reverts[module]; ok {
+		p.stop()
+		delete(r.reverts, module)
+	}
+	delete(r.modules, module)
+	r.updateMin()
+}
+
+// resetAll removes every module override and pending revert
+func (r *levelRegistry) resetAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for m, p := range r.reverts {
+		p.stop()
+		delete(r.reverts, m)
+	}
+	r.modules = make(map[string]zap.AtomicLevel)
+	r.updateMin()
+}
+
+// revert restores the level of module from before its temporary change. If only is not nil the
+// change is reverted only if it is still pending, a timer may fire after a newer change replaced it.
+func (r *levelRegistry) revert(module string, only *pendingRevert) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.reverts[module]
+	if !ok || (only != nil && p != only) {
+		return
+	}
+	p.stop()
+	delete(r.reverts, module)
+	if !p.isSet {
+		delete(r.modules, module)
+		r.updateMin()
+		return
+	}
+	r.store(module, p.level)
+}
+
+func (r *levelRegistry) revertAll() {
+	r.mu.RLock()
+	modules := make([]string, 0, len(r.reverts))
+	for m := range r.reverts {
+		modules = append(modules, m)
+	}
+	r.mu.RUnlock()
+	for _, m := range modules {
+		r.revert(m, nil)
+	}
+}
+
+func (r *levelRegistry) all() (zapcore.Level, map[string]zapcore.Level) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	modules := make(map[string]zapcore.Level, len(r.modules))
+	for m, al := range r.modules {
+		modules[m] = al.Level()
+	}
+	return r.root.Level(), modules
+}
+
+func (r *levelRegistry) snapshot() levelState {
+	root, modules := r.all()
+	st := levelState{Root: root.String(), Modules: make(map[string]string, len(modules))}
+	for m, l := range modules {
+		st.Modules[m] = l.String()
+	}
+	return st
+}
+
+// levelCore filters entries using the level registry instead of a fixed level
+type levelCore struct {
+	zapcore.Core
+	reg *levelRegistry
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= c.reg.min() && c.Core.Enabled(lvl)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), reg: c.reg}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.reg.enabled(ent.LoggerName, ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// SetLevel sets the level of a module, or the root level if module is empty
+func SetLevel(module string, lvl zapcore.Level) {
+	levels.set(module, lvl, false, 0)
+}
+
+// SetLevelFor sets the level of a module for d, after which the previous level is restored.
+// If d is not positive the change lasts until RevertLevels is called.
+func SetLevelFor(module string, lvl zapcore.Level, d time.Duration) {
+	levels.set(module, lvl, true, d)
+}
+
+// GetLevel returns the level configured for module, falling back to the root level
+func GetLevel(module string) zapcore.Level {
+	lvl, _ := levels.get(module)
+	return lvl
+}
+
+// ResetLevel removes the override of a module so it follows the root level again
+func ResetLevel(module string) {
+	levels.reset(module)
+}
+
+// RevertLevels immediately restores every level changed with SetLevelFor
+func RevertLevels() {
+	levels.revertAll()
+}
+
+// Levels returns the root level and all module overrides
+func Levels() (root zapcore.Level, modules map[string]zapcore.Level) {
+	return levels.all()
+}
+
+type levelState struct {
+	Root    string            `json:"root"`
+	Modules map[string]string `json:"modules"`
+}
+
+// levelRequest is the body accepted by LevelHandler on PUT.
+// An empty level resets the module override.
+type levelRequest struct {
+	Module      string `json:"module"`
+	Level       string `json:"level"`
+	RevertAfter string `json:"revertAfter"`
+}
+
+// LevelHandler returns an http.Handler which reports levels on GET and changes
+// them on PUT with a body like {"module":"mongodb","level":"debug","revertAfter":"10m"}.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var req levelRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeLevelError(w, "invalid request body: "+err.Error())
+				return
+			}
+			if err := applyLevelRequest(req); err != nil {
+				writeLevelError(w, err.Error())
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, levels.snapshot())
+	})
+}
+
+func applyLevelRequest(req levelRequest) error {
+	if req.Level == "" {
+		if req.Module == "" {
+			return New(CodeInvalidArgument, "root level can not be reset")
+		}
+		ResetLevel(req.Module)
+		return nil
+	}
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(req.Level)); err != nil {
+		return err
+	}
+	if req.RevertAfter == "" {
+		SetLevel(req.Module, lvl)
+		return nil
+	}
+	d, err := time.ParseDuration(req.RevertAfter)
+	if err != nil {
+		return err
+	}
+	SetLevelFor(req.Module, lvl, d)
+	return nil
+}
+
+func writeLevelError(w http.ResponseWriter, msg string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
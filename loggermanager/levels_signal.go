@@ -0,0 +1,42 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package loggermanager
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// WatchSignals switches the root level to debug on SIGUSR1 and reverts all
+// temporary level changes on SIGHUP. If debugFor is positive the switch to
+// debug also reverts on its own after debugFor.
+// Call the returned func to stop watching.
+func WatchSignals(debugFor time.Duration) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				switch sig {
+				case syscall.SIGUSR1:
+					SetLevelFor("", zapcore.DebugLevel, debugFor)
+				case syscall.SIGHUP:
+					RevertLevels()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
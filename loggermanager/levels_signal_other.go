@@ -0,0 +1,11 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package loggermanager
+
+import "time"
+
+// WatchSignals is a no-op on platforms without SIGUSR1
+func WatchSignals(debugFor time.Duration) (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,140 @@
+package loggermanager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLevelRegistryModules(t *testing.T) {
+	reg := newLevelRegistry(zapcore.InfoLevel)
+	reg.set("mongodb", zapcore.DebugLevel, false, 0)
+	reg.set("cachemanager", zapcore.ErrorLevel, false, 0)
+
+	tests := []struct {
+		name string
+		lvl  zapcore.Level
+		want bool
+	}{
+		{"mongodb", zapcore.DebugLevel, true},
+		{"mongodb.gridfs", zapcore.DebugLevel, true},
+		{"cachemanager", zapcore.WarnLevel, false},
+		{"authmanager", zapcore.DebugLevel, false},
+		{"authmanager", zapcore.InfoLevel, true},
+		{"", zapcore.InfoLevel, true},
+	}
+	for _, tt := range tests {
+		if got := reg.enabled(tt.name, tt.lvl); got != tt.want {
+			t.Errorf("enabled(%q, %v) = %v, want %v", tt.name, tt.lvl, got, tt.want)
+		}
+	}
+	if got := reg.min(); got != zapcore.DebugLevel {
+		t.Errorf("min() = %v, want debug", got)
+	}
+}
+
+func TestLevelRegistryRevert(t *testing.T) {
+	reg := newLevelRegistry(zapcore.InfoLevel)
+	reg.set("", zapcore.DebugLevel, true, 20*time.Millisecond)
+	reg.set("mongodb", zapcore.DebugLevel, true, 0)
+
+	if lvl, _ := reg.get(""); lvl != zapcore.DebugLevel {
+		t.Fatalf("root = %v, want debug", lvl)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if lvl, _ := reg.get(""); lvl != zapcore.InfoLevel {
+		t.Errorf("root after timeout = %v, want info", lvl)
+	}
+
+	reg.revertAll()
+	if _, ok := reg.get("mongodb"); ok {
+		t.Error("mongodb override not removed by revertAll")
+	}
+}
+
+func TestLevelRegistryStaleRevert(t *testing.T) {
+	reg := newLevelRegistry(zapcore.InfoLevel)
+	reg.set("mongodb", zapcore.DebugLevel, true, time.Hour)
+	stale := reg.reverts["mongodb"]
+	reg.set("mongodb", zapcore.WarnLevel, true, time.Hour)
+
+	// the timer of the first change fired while the second one was being set
+	reg.revert("mongodb", stale)
+	if lvl, _ := reg.get("mongodb"); lvl != zapcore.WarnLevel {
+		t.Errorf("mongodb = %v, want warn until the second change expires", lvl)
+	}
+	reg.revertAll()
+	if _, ok := reg.get("mongodb"); ok {
+		t.Error("mongodb override not removed by revertAll")
+	}
+}
+
+func TestLevelRegistryMin(t *testing.T) {
+	reg := newLevelRegistry(zapcore.InfoLevel)
+	steps := []struct {
+		name   string
+		change func()
+		want   zapcore.Level
+	}{
+		{"set", func() { reg.set("mongodb", zapcore.DebugLevel, false, 0) }, zapcore.DebugLevel},
+		{"raise", func() { reg.set("mongodb", zapcore.ErrorLevel, false, 0) }, zapcore.InfoLevel},
+		{"temporary", func() { reg.set("cachemanager", zapcore.DebugLevel, true, 0) }, zapcore.DebugLevel},
+		{"revert", func() { reg.revertAll() }, zapcore.InfoLevel},
+		{"root", func() { reg.set("", zapcore.WarnLevel, false, 0) }, zapcore.WarnLevel},
+		{"reset", func() { reg.reset("") }, zapcore.WarnLevel},
+		{"resetAll", func() { reg.set("authmanager", zapcore.DebugLevel, false, 0); reg.resetAll() }, zapcore.WarnLevel},
+	}
+	for _, step := range steps {
+		step.change()
+		if got := reg.min(); got != step.want {
+			t.Errorf("%s: min() = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestLevelCoreFiltersByName(t *testing.T) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	reg := newLevelRegistry(zapcore.WarnLevel)
+	reg.set("mongodb", zapcore.DebugLevel, false, 0)
+	l := zap.New(&levelCore{Core: obs, reg: reg})
+
+	l.Named("mongodb").Debug("kept")
+	l.Named("cachemanager").Info("dropped")
+	l.Warn("kept")
+
+	if n := logs.Len(); n != 2 {
+		t.Errorf("got %d entries, want 2", n)
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	defer ResetLevel("testmodule")
+	h := LevelHandler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"module":"testmodule","level":"error"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, body %s", rec.Code, rec.Body)
+	}
+	if lvl := GetLevel("testmodule"); lvl != zapcore.ErrorLevel {
+		t.Errorf("level = %v, want error", lvl)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(rec.Body.String(), `"testmodule":"error"`) {
+		t.Errorf("GET body = %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"module":"testmodule","level":"loud"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid level status = %d, want 400", rec.Code)
+	}
+}
@@ -1,49 +1,93 @@
 package loggermanager
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
-	logging "github.com/op/go-logging"
-)
-
-var log = logging.MustGetLogger("crearosoftlogger")
-var format = logging.MustStringFormatter(
-	`%{color}%{time:15:04:05} %{shortfile} %{callpath:5} ▶ %{level:.4s} %{id:03x}%{color:reset}`,
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func init() {
+	SetLogger(NewZapLogger(newDefaultZap()))
+}
 
-	log.ExtraCalldepth = 1
-	backend := logging.NewLogBackend(os.Stderr, "", 0)
-
-	backendFormatter := logging.NewBackendFormatter(backend, format)
-	backendLeveled := logging.AddModuleLevel(backend)
+// newDefaultZap writes colored console output to stderr until Init or SetLogger is called
+func newDefaultZap() *zap.Logger {
+	encCfg := zap.NewDevelopmentEncoderConfig()
+	encCfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	core := zapcore.NewCore(zapcore.NewConsoleEncoder(encCfg), zapcore.Lock(os.Stderr), zapcore.DebugLevel)
+	return zap.New(wrapCore(core), zap.AddCaller())
+}
 
-	logging.SetBackend(backendLeveled, backendFormatter)
+// sprint joins args with spaces the way the go-logging backend used to
+func sprint(args ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
 
+// splitArgs pulls typed Fields out of args so they are logged as fields instead of message text.
+// Redactable args are formatted in their redacted form.
+func splitArgs(args []interface{}) (string, []interface{}) {
+	var fields []interface{}
+	rest := make([]interface{}, 0, len(args))
+	for _, a := range args {
+		if f, ok := a.(Field); ok {
+			fields = append(fields, f)
+			continue
+		}
+		rest = append(rest, RedactValue(a))
+	}
+	return sprint(rest...), fields
 }
 
 // LogDebug logs a message at level Debug on the standard logger.
+// Field arguments are logged as fields, everything else is joined into the message.
 func LogDebug(args ...interface{}) {
-	log.Debug("", args)
+	msg, fields := splitArgs(args)
+	current().Debug(msg, fields...)
 }
 
 // LogInfo logs a message at level Info on the standard logger.
 func LogInfo(args ...interface{}) {
-	log.Info("", args)
+	msg, fields := splitArgs(args)
+	current().Info(msg, fields...)
 }
 
 // LogWarn logs a message at level Warn on the standard logger.
 func LogWarn(args ...interface{}) {
-	log.Warning("", args)
+	msg, fields := splitArgs(args)
+	current().Warn(msg, fields...)
 }
 
 // LogError logs a message at level Error on the standard logger.
 func LogError(args ...interface{}) {
-	log.Error("", args)
+	msg, fields := splitArgs(args)
+	current().Error(msg, fields...)
 }
 
 // LogPanic logs a message at level Panic on the standard logger.
 func LogPanic(args ...interface{}) {
-	log.Panic("", args)
+	msg, fields := splitArgs(args)
+	current().Panic(msg, fields...)
+}
+
+// LogDebugw logs a message with key/value pairs at level Debug on the standard logger.
+func LogDebugw(msg string, keysAndValues ...interface{}) {
+	current().Debug(msg, keysAndValues...)
+}
+
+// LogInfow logs a message with key/value pairs at level Info on the standard logger.
+func LogInfow(msg string, keysAndValues ...interface{}) {
+	current().Info(msg, keysAndValues...)
+}
+
+// LogWarnw logs a message with key/value pairs at level Warn on the standard logger.
+func LogWarnw(msg string, keysAndValues ...interface{}) {
+	current().Warn(msg, keysAndValues...)
+}
+
+// LogErrorw logs a message with key/value pairs at level Error on the standard logger.
+func LogErrorw(msg string, keysAndValues ...interface{}) {
+	current().Error(msg, keysAndValues...)
 }
@@ -1,61 +1,109 @@
 package loggermanager
 
 import (
-	"encoding/json"
-	"os"
-	"path/filepath"
+	"sync"
 
-	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	lumberjack "gopkg.in/natefinch/lumberjack.v2"
 )
 
-var logger *zap.Logger
-var sugar *zap.SugaredLogger
+// Logger is the leveled, structured logging contract used across corelib.
+// keysAndValues are loosely typed pairs, e.g. Info("key saved", "key", k).
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+	Panic(msg string, keysAndValues ...interface{})
+
+	// With returns a child logger which adds keysAndValues to every entry
+	With(keysAndValues ...interface{}) Logger
+	// Named returns a child logger whose name is suffixed with name
+	Named(name string) Logger
+
+	Sync() error
+}
+
+// callerSkipper is implemented by loggers which can report the caller of a wrapper function
+type callerSkipper interface {
+	withCallerSkip(skip int) Logger
+}
+
+var (
+	mu         sync.RWMutex
+	std        Logger // logger handed out by GetLogger
+	pkgStd     Logger // std adjusted for one extra frame of the LogX functions
+	generation uint64 // bumped on every SetLogger so lazy loggers can re-resolve
+)
+
+// SetLogger replaces the backend used by the package level LogX functions
+func SetLogger(l Logger) {
+	if l == nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	std = l
+	pkgStd = skipCaller(l, 1)
+	generation++
+}
+
+// GetLogger returns the logger currently in use
+func GetLogger() Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+	return std
+}
+
+// Sync flushes any buffered log entries
+func Sync() error {
+	return GetLogger().Sync()
+}
+
+func current() Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+	return pkgStd
+}
+
+func skipCaller(l Logger, skip int) Logger {
+	if cs, ok := l.(callerSkipper); ok {
+		return cs.withCallerSkip(skip)
+	}
+	return l
+}
 
 // Init  Init Logger
+// loglevel sets the root level, see SetLevel to change it at runtime
 // maxBackupFileSize,  megabytes
 // maxAgeForBackupFile,  days
+//
+// Init is a shorthand for InitFromConfig with a single file sink.
+// The file keeps the JSON keys written by older releases: L, T, M and C.
 func Init(fileName string, maxBackupCnt, maxBackupFileSize, maxAgeForBackupFileInDays int, loglevel zapcore.Level) {
-	os.MkdirAll(filepath.Dir(fileName), os.ModePerm)
-
-	w := zapcore.AddSync(&lumberjack.Logger{
-		Filename:   fileName,
-		MaxSize:    maxBackupFileSize, // megabytes
-		MaxBackups: maxBackupCnt,
-		MaxAge:     maxAgeForBackupFileInDays, // days
+	err := InitFromConfig(Config{
+		Level: loglevel.String(),
+		Sinks: []SinkConfig{{
+			Type:       SinkFile,
+			Filename:   fileName,
+			MaxSize:    maxBackupFileSize, // megabytes
+			MaxBackups: maxBackupCnt,
+			MaxAge:     maxAgeForBackupFileInDays, // days
+			legacyKeys: true,
+		}},
 	})
+	if err != nil {
+		LogError("logger init failed", Err(err))
+	}
+}
 
-	// zap.AddStacktrace(
-	rawJSON := []byte(`{
-	  "level": "debug",
-	  "encoding": "json",
-	  "outputPaths": ["stdout", "./data/logs"],
-	  "errorOutputPaths": ["stderr"],
-	  "initialFields": {"foo": "bar"},
-	  "disableCaller":false,
-	  "encoderConfig": {
-	    "messageKey": "m",
-	    "callerKey": "c",
-	    "callerEncode": 0,
-	    "timeKey": "t",
-		"levelKey": "l",
-	    "levelEncoder": "lowercase"
-	  }
-	}`)
-
-	var cfg zap.Config
-	json.Unmarshal(rawJSON, &cfg)
-	core := zapcore.NewCore(
-		//enc, //
-		zapcore.NewJSONEncoder(zap.NewDevelopmentEncoderConfig()),
-		w,
-		loglevel,
-	)
-
-	logger = zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))
-
-	defer logger.Sync()
-	sugar = logger.Sugar()
-
+// wrapCore combines backend cores and applies the package wide filters.
+// Redaction wraps each backend and the AddCore hooks, sampling and levels apply to all of them.
+func wrapCore(backends ...zapcore.Core) zapcore.Core {
+	cores := make([]zapcore.Core, len(backends), len(backends)+1)
+	for i := range backends {
+		cores[i] = &redactCore{Core: backends[i]}
+	}
+	cores = append(cores, &redactCore{Core: &hookCore{}})
+	tee := zapcore.NewTee(cores...)
+	return &levelCore{Core: &samplingCore{Core: tee, base: &levelCore{Core: tee, reg: levels}}, reg: levels}
 }
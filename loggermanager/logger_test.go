@@ -0,0 +1,91 @@
+package loggermanager
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// recordingLogger is a Logger backend which is not zap based
+type recordingLogger struct {
+	calls []string
+}
+
+func (r *recordingLogger) record(level, msg string) { r.calls = append(r.calls, level+" "+msg) }
+
+func (r *recordingLogger) Debug(msg string, _ ...interface{}) { r.record("debug", msg) }
+func (r *recordingLogger) Info(msg string, _ ...interface{})  { r.record("info", msg) }
+func (r *recordingLogger) Warn(msg string, _ ...interface{})  { r.record("warn", msg) }
+func (r *recordingLogger) Error(msg string, _ ...interface{}) { r.record("error", msg) }
+func (r *recordingLogger) Panic(msg string, _ ...interface{}) { r.record("panic", msg) }
+func (r *recordingLogger) With(...interface{}) Logger         { return r }
+func (r *recordingLogger) Named(string) Logger                { return r }
+func (r *recordingLogger) Sync() error                        { return nil }
+
+func TestSetLogger(t *testing.T) {
+	prev := GetLogger()
+	t.Cleanup(func() { SetLogger(prev) })
+
+	rec := &recordingLogger{}
+	SetLogger(rec)
+	SetLogger(nil)
+	if GetLogger() != Logger(rec) {
+		t.Fatal("SetLogger(nil) replaced the logger")
+	}
+	LogInfo("saved", 3)
+	LogWarnw("slow", "millis", 900)
+	Named("cachemanager").Error("failed")
+	if want := []string{"info saved 3", "warn slow", "error failed"}; len(rec.calls) != len(want) ||
+		rec.calls[0] != want[0] || rec.calls[1] != want[1] || rec.calls[2] != want[2] {
+		t.Errorf("calls = %q, want %q", rec.calls, want)
+	}
+}
+
+func TestLogRouting(t *testing.T) {
+	prev := GetLogger()
+	core, logs := observer.New(zapcore.DebugLevel)
+	SetLogger(NewZapLogger(zap.New(core, zap.AddCaller())))
+	t.Cleanup(func() { SetLogger(prev) })
+
+	tests := []struct {
+		name  string
+		log   func()
+		level zapcore.Level
+		msg   string
+	}{
+		{"LogDebug", func() { LogDebug("cache", "miss", String("key", "k1")) }, zapcore.DebugLevel, "cache miss"},
+		{"LogInfo", func() { LogInfo("loaded", 3, "hosts", String("key", "k1")) }, zapcore.InfoLevel, "loaded 3 hosts"},
+		{"LogWarn", func() { LogWarn(String("key", "k1"), "slow") }, zapcore.WarnLevel, "slow"},
+		{"LogError", func() { LogError("failed", String("key", "k1")) }, zapcore.ErrorLevel, "failed"},
+		{"LogDebugw", func() { LogDebugw("cache miss", "key", "k1") }, zapcore.DebugLevel, "cache miss"},
+		{"LogInfow", func() { LogInfow("loaded", "key", "k1") }, zapcore.InfoLevel, "loaded"},
+		{"LogWarnw", func() { LogWarnw("slow", String("key", "k1")) }, zapcore.WarnLevel, "slow"},
+		{"LogErrorw", func() { LogErrorw("failed", "key", "k1") }, zapcore.ErrorLevel, "failed"},
+		{"LogPanic", func() {
+			defer func() { recover() }()
+			LogPanic("broken", String("key", "k1"))
+		}, zapcore.PanicLevel, "broken"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.log()
+			entries := logs.TakeAll()
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			e := entries[0]
+			if e.Level != tt.level || e.Message != tt.msg {
+				t.Errorf("entry = %s %q, want %s %q", e.Level, e.Message, tt.level, tt.msg)
+			}
+			if fields := e.ContextMap(); len(fields) != 1 || fields["key"] != "k1" {
+				t.Errorf("fields = %v", fields)
+			}
+			if filepath.Base(e.Caller.File) != "logger_test.go" {
+				t.Errorf("caller = %s", e.Caller.TrimmedPath())
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package logquery
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupTime matches the times both rotators put in backup names: the daily, hourly and size
+// patterns of the file sink, e.g. 2006-01-02T15-04-05, and lumberjack's 2006-01-02T15-04-05.000
+const backupTime = `\d{4}-\d{2}-\d{2}(T\d{2}(-\d{2}-\d{2}(\.\d{3})?)?)?`
+
+// Files returns the rotated backups of filename, oldest first, followed by filename itself.
+// Backups are found by the naming of both rotators, "<name>-<time><ext>" with optional ".N" and ".gz".
+func Files(filename string) ([]string, error) {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	names, err := filepath.Glob(base + "-*" + ext + "*")
+	if err != nil {
+		return nil, err
+	}
+	// the glob matches sibling files too, e.g. app-errors.log for app.log
+	re := regexp.MustCompile("^" + regexp.QuoteMeta(filepath.Base(base)) + "-" + backupTime +
+		regexp.QuoteMeta(ext) + `(\.\d+)?(\.gz)?$`)
+	type file struct {
+		name string
+		mod  time.Time
+	}
+	var backups []file
+	for _, name := range names {
+		if name == filename || !re.MatchString(filepath.Base(name)) {
+			continue
+		}
+		if info, err := os.Stat(name); err == nil && info.Mode().IsRegular() {
+			backups = append(backups, file{name, info.ModTime()})
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].mod.Before(backups[j].mod) })
+	out := make([]string, 0, len(backups)+1)
+	for _, b := range backups {
+		out = append(out, b.name)
+	}
+	return append(out, filename), nil
+}
+
+// Open opens a log file, gzip compressed files are decompressed
+func Open(name string) (io.ReadCloser, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(name, ".gz") {
+		return f, nil
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return gzipFile{Reader: zr, f: f}, nil
+}
+
+type gzipFile struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (g gzipFile) Close() error {
+	g.Reader.Close()
+	return g.f.Close()
+}
+
+// ReadLines calls fn for every line of name and returns the number of bytes read,
+// which is where Follow continues for uncompressed files
+func ReadLines(name string, fn func(line []byte)) (int64, error) {
+	rc, err := Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	var n int64
+	br := bufio.NewReader(rc)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			// a partial last line is left to Follow unless the file is compressed
+			if len(line) > 0 && strings.HasSuffix(name, ".gz") {
+				fn(bytes.TrimRight(line, "\r\n"))
+			}
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		n += int64(len(line))
+		fn(bytes.TrimRight(line, "\r\n"))
+	}
+}
+
+// Follow calls fn for every line appended to filename after offset until ctx is done.
+// It notices rotation and truncation by polling every interval.
+func Follow(ctx context.Context, filename string, offset int64, interval time.Duration, fn func(line []byte)) error {
+	var (
+		f       *os.File
+		info    os.FileInfo
+		br      *bufio.Reader
+		pending []byte
+	)
+	open := func(at int64) error {
+		var err error
+		if f, err = os.Open(filename); err != nil {
+			return err
+		}
+		if info, err = f.Stat(); err != nil {
+			return err
+		}
+		if at > info.Size() {
+			at = 0
+		}
+		if _, err = f.Seek(at, io.SeekStart); err != nil {
+			return err
+		}
+		offset = at
+		br = bufio.NewReader(f)
+		pending = pending[:0]
+		return nil
+	}
+	// drain reads the complete lines written so far
+	drain := func() error {
+		for {
+			chunk, err := br.ReadBytes('\n')
+			pending = append(pending, chunk...)
+			offset += int64(len(chunk))
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			fn(bytes.TrimRight(pending, "\r\n"))
+			pending = pending[:0]
+		}
+	}
+
+	for f == nil {
+		if err := open(offset); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if f == nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(interval):
+			}
+		}
+	}
+	defer func() { f.Close() }()
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := drain(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+		now, err := os.Stat(filename)
+		switch {
+		case err != nil:
+			// between the rename and the creation of the new file
+			continue
+		case !os.SameFile(info, now):
+			if err := drain(); err != nil {
+				return err
+			}
+			f.Close()
+			if err := open(0); err != nil {
+				return err
+			}
+		case now.Size() < offset:
+			f.Close()
+			if err := open(0); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package logquery
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const prettyTimeLayout = "2006-01-02T15:04:05.000Z0700"
+
+var levelColors = map[zapcore.Level]string{
+	zapcore.DebugLevel: "\x1b[35m",
+	zapcore.InfoLevel:  "\x1b[34m",
+	zapcore.WarnLevel:  "\x1b[33m",
+}
+
+// WritePretty prints r on one line: time, level, logger, caller, message and sorted key=value fields
+func WritePretty(w io.Writer, r Record, color bool) error {
+	var sb strings.Builder
+	if !r.Time.IsZero() {
+		sb.WriteString(r.Time.Format(prettyTimeLayout))
+		sb.WriteByte('\t')
+	}
+	lvl := r.Level.CapitalString()
+	if color {
+		c, ok := levelColors[r.Level]
+		if !ok {
+			c = "\x1b[31m"
+		}
+		lvl = c + lvl + "\x1b[0m"
+	}
+	sb.WriteString(lvl)
+	for _, s := range []string{r.Logger, r.Caller, r.Message} {
+		if s != "" {
+			sb.WriteByte('\t')
+			sb.WriteString(s)
+		}
+	}
+	keys := make([]string, 0, len(r.Fields))
+	for k := range r.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		if i == 0 {
+			sb.WriteByte('\t')
+		} else {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		switch v := r.Fields[k].(type) {
+		case string:
+			if strings.ContainsAny(v, " \t\n\"=") {
+				b, _ := json.Marshal(v)
+				sb.Write(b)
+			} else {
+				sb.WriteString(v)
+			}
+		default:
+			b, _ := json.Marshal(v)
+			sb.Write(b)
+		}
+	}
+	sb.WriteByte('\n')
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteJSON prints r as one JSON line with the keys of the current encoding,
+// so files of older releases come out in today's format
+func WriteJSON(w io.Writer, r Record) error {
+	m := make(map[string]interface{}, len(r.Fields)+5)
+	for k, v := range r.Fields {
+		m[k] = v
+	}
+	if !r.Time.IsZero() {
+		m[currentKeys.time] = r.Time.Format(prettyTimeLayout)
+	}
+	m[currentKeys.level] = r.Level.String()
+	m[currentKeys.msg] = r.Message
+	if r.Logger != "" {
+		m[currentKeys.logger] = r.Logger
+	}
+	if r.Caller != "" {
+		m[currentKeys.caller] = r.Caller
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// ParseTime reads an RFC 3339 time or a duration before now, e.g. "90m"
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range append([]string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}, timeLayouts...) {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, &time.ParseError{Layout: time.RFC3339, Value: s, Message: ": use RFC 3339 or a duration like 90m"}
+}
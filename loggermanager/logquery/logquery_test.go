@@ -0,0 +1,160 @@
+package logquery
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseEncodings(t *testing.T) {
+	for name, line := range map[string]string{
+		"current": `{"level":"warn","ts":"2024-03-01T10:00:00.000Z","logger":"mongodb","caller":"mongodb/mongodb.go:42","msg":"slow query","millis":900}`,
+		"legacy":  `{"L":"WARN","T":"2024-03-01T10:00:00.000Z","N":"mongodb","C":"mongodb/mongodb.go:42","M":"slow query","millis":900}`,
+		"otel":    `{"Timestamp":"1709287200000000000","SeverityText":"WARN","SeverityNumber":13,"Body":"slow query","InstrumentationScope":{"Name":"mongodb"},"Attributes":{"code.filepath":"mongodb/mongodb.go","code.lineno":42,"millis":900}}`,
+	} {
+		r, err := Parse([]byte(line))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !r.Time.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) || r.Level != zapcore.WarnLevel || r.Logger != "mongodb" ||
+			r.Caller != "mongodb/mongodb.go:42" || r.Message != "slow query" || len(r.Fields) != 1 {
+			t.Errorf("%s: %+v", name, r)
+		}
+		f := Filter{Level: zapcore.WarnLevel, Logger: "mongodb", Caller: "mongodb.go", Fields: []FieldMatch{ParseFieldMatch("millis=900")}}
+		if !f.Match(r) {
+			t.Errorf("%s: filter does not match", name)
+		}
+	}
+	if _, err := Parse([]byte("plain text")); err != ErrNotJSON {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	r, _ := Parse([]byte(`{"level":"info","ts":"2024-03-01T10:00:00.000Z","logger":"mongodb.gridfs","msg":"uploaded","file":"a.png"}`))
+	at := func(s string) time.Time {
+		ts, _ := time.Parse(time.RFC3339, s)
+		return ts
+	}
+	for _, f := range []Filter{
+		{Level: zapcore.WarnLevel},
+		{Since: at("2024-03-01T11:00:00Z")},
+		{Until: at("2024-03-01T09:00:00Z")},
+		{Logger: "mongo"},
+		{Message: "deleted"},
+		{Fields: []FieldMatch{{Key: "file", Value: "b.png"}}},
+		{Fields: []FieldMatch{{Key: "size"}}},
+	} {
+		if f.Match(r) {
+			t.Errorf("%+v matched", f)
+		}
+	}
+	if f := (Filter{Logger: "mongodb", Since: at("2024-03-01T09:00:00Z"), Fields: []FieldMatch{{Key: "file"}}}); !f.Match(r) {
+		t.Error("filter did not match")
+	}
+}
+
+func TestFilesAndGzip(t *testing.T) {
+	dir := t.TempDir()
+	active := filepath.Join(dir, "app.log")
+	write := func(name, content string, age time.Duration, compress bool) {
+		var buf bytes.Buffer
+		if compress {
+			zw := gzip.NewWriter(&buf)
+			zw.Write([]byte(content))
+			zw.Close()
+		} else {
+			buf.WriteString(content)
+		}
+		os.WriteFile(name, buf.Bytes(), 0644)
+		mod := time.Now().Add(-age)
+		os.Chtimes(name, mod, mod)
+	}
+	write(filepath.Join(dir, "app-2024-03-02.log"), `{"level":"info","msg":"two"}`+"\n", 24*time.Hour, false)
+	write(filepath.Join(dir, "app-2024-03-01.log.gz"), `{"level":"info","msg":"one"}`, 48*time.Hour, true)
+	write(active, `{"level":"info","msg":"three"}`+"\n"+`{"level":"info","msg":"partial`, 0, false)
+	write(filepath.Join(dir, "other.log"), `{"level":"info","msg":"other"}`+"\n", 0, false)
+	// sibling sinks and their backups
+	write(filepath.Join(dir, "app-errors.log"), `{"level":"error","msg":"sibling"}`+"\n", 0, false)
+	write(filepath.Join(dir, "app-errors-2024-03-02.log"), `{"level":"error","msg":"sibling"}`+"\n", 0, false)
+
+	files, err := Files(active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msgs []string
+	var offset int64
+	for _, f := range files {
+		offset, err = ReadLines(f, func(line []byte) {
+			r, _ := Parse(line)
+			msgs = append(msgs, r.Message)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Join(msgs, ",") != "one,two,three" {
+		t.Errorf("messages = %v", msgs)
+	}
+	if offset != int64(len(`{"level":"info","msg":"three"}`)+1) {
+		t.Errorf("offset = %d", offset)
+	}
+}
+
+func TestFollowAcrossRotation(t *testing.T) {
+	dir := t.TempDir()
+	active := filepath.Join(dir, "app.log")
+	os.WriteFile(active, []byte("old\n"), 0644)
+
+	var (
+		mu    sync.Mutex
+		lines []string
+	)
+	got := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return strings.Join(lines, ",")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Follow(ctx, active, 4, 5*time.Millisecond, func(line []byte) {
+			mu.Lock()
+			lines = append(lines, string(line))
+			mu.Unlock()
+		})
+	}()
+	waitFor := func(want string) {
+		t.Helper()
+		for i := 0; i < 400 && got() != want; i++ {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if got() != want {
+			t.Fatalf("lines = %q, want %q", got(), want)
+		}
+	}
+
+	f, _ := os.OpenFile(active, os.O_APPEND|os.O_WRONLY, 0644)
+	f.Write([]byte("a\nb"))
+	waitFor("a")
+	f.Write([]byte("\n"))
+	f.Close()
+	waitFor("a,b")
+
+	os.Rename(active, filepath.Join(dir, "app-1.log"))
+	os.WriteFile(active, []byte("c\n"), 0644)
+	waitFor("a,b,c")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,187 @@
+// Package logquery reads, filters and prints the JSON line files written by loggermanager.
+//
+// It understands the current encoding (ts, level, logger, caller, msg), the development
+// encoding of older releases (T, L, N, C, M) and OpenTelemetry records.
+package logquery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Record is one parsed log line
+type Record struct {
+	Time    time.Time
+	Level   zapcore.Level
+	Logger  string
+	Caller  string
+	Message string
+	Fields  map[string]interface{} // everything else
+}
+
+// key sets of the supported encodings
+type keySet struct {
+	time, level, logger, caller, msg string
+}
+
+var (
+	currentKeys = keySet{"ts", "level", "logger", "caller", "msg"}
+	legacyKeys  = keySet{"T", "L", "N", "C", "M"}
+)
+
+var timeLayouts = []string{
+	"2006-01-02T15:04:05.000Z0700",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.000Z07:00",
+}
+
+// ErrNotJSON is returned by Parse for lines which are not JSON objects
+var ErrNotJSON = errors.New("not a json log line")
+
+// Parse reads one line of any supported encoding
+func Parse(line []byte) (Record, error) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '{' {
+		return Record{}, ErrNotJSON
+	}
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return Record{}, ErrNotJSON
+	}
+	if _, ok := m["SeverityText"]; ok {
+		if _, ok := m["Body"]; ok {
+			return parseOTel(m), nil
+		}
+	}
+	keys := currentKeys
+	if _, ok := m[currentKeys.msg]; !ok {
+		if _, ok := m[legacyKeys.msg]; ok {
+			keys = legacyKeys
+		}
+	}
+	r := Record{
+		Time:    parseTime(m[keys.time]),
+		Logger:  str(m[keys.logger]),
+		Caller:  str(m[keys.caller]),
+		Message: str(m[keys.msg]),
+	}
+	r.Level.UnmarshalText([]byte(str(m[keys.level])))
+	for _, k := range []string{keys.time, keys.level, keys.logger, keys.caller, keys.msg} {
+		delete(m, k)
+	}
+	r.Fields = m
+	return r, nil
+}
+
+func parseOTel(m map[string]interface{}) Record {
+	r := Record{Message: str(m["Body"]), Fields: make(map[string]interface{})}
+	if ns, err := strconv.ParseInt(str(m["Timestamp"]), 10, 64); err == nil {
+		r.Time = time.Unix(0, ns)
+	}
+	r.Level.UnmarshalText([]byte(str(m["SeverityText"])))
+	if scope, ok := m["InstrumentationScope"].(map[string]interface{}); ok {
+		r.Logger = str(scope["Name"])
+	}
+	for _, group := range []string{"Resource", "Attributes"} {
+		if attrs, ok := m[group].(map[string]interface{}); ok {
+			for k, v := range attrs {
+				r.Fields[k] = v
+			}
+		}
+	}
+	if file, ok := r.Fields["code.filepath"]; ok {
+		r.Caller = str(file) + ":" + str(r.Fields["code.lineno"])
+		delete(r.Fields, "code.filepath")
+		delete(r.Fields, "code.lineno")
+	}
+	if id := str(m["TraceId"]); id != "" {
+		r.Fields["trace_id"] = id
+	}
+	if id := str(m["SpanId"]); id != "" {
+		r.Fields["span_id"] = id
+	}
+	return r
+}
+
+func parseTime(v interface{}) time.Time {
+	switch t := v.(type) {
+	case string:
+		for _, layout := range timeLayouts {
+			if ts, err := time.Parse(layout, t); err == nil {
+				return ts
+			}
+		}
+	case json.Number:
+		// epoch seconds of zap's production encoder
+		if f, err := t.Float64(); err == nil {
+			sec := int64(f)
+			return time.Unix(sec, int64((f-float64(sec))*1e9))
+		}
+	}
+	return time.Time{}
+}
+
+func str(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	}
+	return fmt.Sprint(v)
+}
+
+// FieldMatch selects records having Key, with Value unless it is empty.
+// Values are compared by their printed form, so 42 matches a number field.
+type FieldMatch struct {
+	Key   string
+	Value string
+}
+
+// ParseFieldMatch reads "key=value" or "key"
+func ParseFieldMatch(s string) FieldMatch {
+	if i := strings.Index(s, "="); i >= 0 {
+		return FieldMatch{Key: s[:i], Value: s[i+1:]}
+	}
+	return FieldMatch{Key: s}
+}
+
+// Filter selects records, zero values match everything
+type Filter struct {
+	Level   zapcore.LevelEnabler // nil keeps every level
+	Since   time.Time
+	Until   time.Time
+	Logger  string // name, children included
+	Caller  string // substring of the caller, e.g. "mongodb.go"
+	Message string // substring of the message
+	Fields  []FieldMatch
+}
+
+// Match reports whether r passes every condition of f
+func (f *Filter) Match(r Record) bool {
+	switch {
+	case f.Level != nil && !f.Level.Enabled(r.Level),
+		!f.Since.IsZero() && r.Time.Before(f.Since),
+		!f.Until.IsZero() && r.Time.After(f.Until),
+		f.Logger != "" && r.Logger != f.Logger && !strings.HasPrefix(r.Logger, f.Logger+"."),
+		f.Caller != "" && !strings.Contains(r.Caller, f.Caller),
+		f.Message != "" && !strings.Contains(r.Message, f.Message):
+		return false
+	}
+	for _, fm := range f.Fields {
+		v, ok := r.Fields[fm.Key]
+		if !ok || (fm.Value != "" && str(v) != fm.Value) {
+			return false
+		}
+	}
+	return true
+}
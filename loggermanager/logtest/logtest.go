@@ -0,0 +1,190 @@
+// Package logtest captures log entries in memory so tests can assert on them.
+//
+//	func TestSave(t *testing.T) {
+//		logs := logtest.Swap(t)
+//		dao.SaveData(...)
+//		logs.AssertLogged(t, zapcore.ErrorLevel, "insert failed")
+//	}
+//
+// Entries are captured at every level, before module levels, sampling and redaction apply.
+package logtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crearosoft/corelib/loggermanager"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// Entry is one captured log entry
+type Entry struct {
+	Level   zapcore.Level
+	Time    time.Time
+	Logger  string // name, e.g. "mongodb"
+	Message string
+	Caller  string // file:line, empty when unknown
+	Fields  map[string]interface{}
+}
+
+func (e Entry) String() string {
+	return fmt.Sprintf("%s %s %q %v", e.Level, e.Logger, e.Message, e.Fields)
+}
+
+// Entries is a list of entries which can be narrowed down by the filter methods
+type Entries []Entry
+
+func (es Entries) filter(keep func(Entry) bool) Entries {
+	var out Entries
+	for _, e := range es {
+		if keep(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Level keeps entries of exactly lvl
+func (es Entries) Level(lvl zapcore.Level) Entries {
+	return es.filter(func(e Entry) bool { return e.Level == lvl })
+}
+
+// AtLeast keeps entries of lvl and above
+func (es Entries) AtLeast(lvl zapcore.Level) Entries {
+	return es.filter(func(e Entry) bool { return e.Level >= lvl })
+}
+
+// Message keeps entries whose message contains substr
+func (es Entries) Message(substr string) Entries {
+	return es.filter(func(e Entry) bool { return strings.Contains(e.Message, substr) })
+}
+
+// Logger keeps entries of the named logger and its children
+func (es Entries) Logger(name string) Entries {
+	return es.filter(func(e Entry) bool { return e.Logger == name || strings.HasPrefix(e.Logger, name+".") })
+}
+
+// Field keeps entries having key. Values are compared by their fmt.Sprint form so Int(1) matches 1 and Err(err) matches err.
+func (es Entries) Field(key string, val interface{}) Entries {
+	want := fmt.Sprint(val)
+	return es.filter(func(e Entry) bool {
+		v, ok := e.Fields[key]
+		return ok && fmt.Sprint(v) == want
+	})
+}
+
+// HasField keeps entries having key, whatever its value
+func (es Entries) HasField(key string) Entries {
+	return es.filter(func(e Entry) bool {
+		_, ok := e.Fields[key]
+		return ok
+	})
+}
+
+// Messages returns the messages in order
+func (es Entries) Messages() []string {
+	out := make([]string, len(es))
+	for i, e := range es {
+		out[i] = e.Message
+	}
+	return out
+}
+
+// Recorder keeps every entry written to its logger
+type Recorder struct {
+	logs   *observer.ObservedLogs
+	logger loggermanager.Logger
+}
+
+// New returns a recorder, use Logger to hand it to the code under test
+func New() *Recorder {
+	core, logs := observer.New(zapcore.DebugLevel)
+	return &Recorder{
+		logs:   logs,
+		logger: loggermanager.NewZapLogger(zap.New(core, zap.AddCaller())),
+	}
+}
+
+// Swap installs a new recorder as the package logger until the test ends.
+// Tests using it must not run in parallel with other tests logging through loggermanager.
+func Swap(t testing.TB) *Recorder {
+	t.Helper()
+	r := New()
+	prev := loggermanager.GetLogger()
+	loggermanager.SetLogger(r.logger)
+	t.Cleanup(func() { loggermanager.SetLogger(prev) })
+	return r
+}
+
+// Logger returns the logger writing to r
+func (r *Recorder) Logger() loggermanager.Logger {
+	return r.logger
+}
+
+// Entries returns the captured entries in order
+func (r *Recorder) Entries() Entries {
+	all := r.logs.All()
+	out := make(Entries, len(all))
+	for i, le := range all {
+		out[i] = Entry{
+			Level:   le.Level,
+			Time:    le.Time,
+			Logger:  le.LoggerName,
+			Message: le.Message,
+			Fields:  le.ContextMap(),
+		}
+		if le.Caller.Defined {
+			out[i].Caller = le.Caller.File + ":" + fmt.Sprint(le.Caller.Line)
+		}
+	}
+	return out
+}
+
+// Len returns the number of captured entries
+func (r *Recorder) Len() int {
+	return r.logs.Len()
+}
+
+// Reset forgets the captured entries
+func (r *Recorder) Reset() {
+	r.logs.TakeAll()
+}
+
+// AssertLogged fails t unless an entry of lvl contains substr, it returns the first match
+func (r *Recorder) AssertLogged(t testing.TB, lvl zapcore.Level, substr string) Entry {
+	t.Helper()
+	found := r.Entries().Level(lvl).Message(substr)
+	if len(found) == 0 {
+		t.Errorf("no %s entry containing %q, got:\n%s", lvl, substr, r.dump())
+		return Entry{}
+	}
+	return found[0]
+}
+
+// AssertNotLogged fails t if an entry of lvl contains substr
+func (r *Recorder) AssertNotLogged(t testing.TB, lvl zapcore.Level, substr string) {
+	t.Helper()
+	if found := r.Entries().Level(lvl).Message(substr); len(found) > 0 {
+		t.Errorf("unexpected %s entry containing %q: %v", lvl, substr, found[0])
+	}
+}
+
+// AssertCount fails t unless exactly n entries were captured at lvl or above
+func (r *Recorder) AssertCount(t testing.TB, lvl zapcore.Level, n int) {
+	t.Helper()
+	if got := len(r.Entries().AtLeast(lvl)); got != n {
+		t.Errorf("%d entries at %s or above, want %d, got:\n%s", got, lvl, n, r.dump())
+	}
+}
+
+func (r *Recorder) dump() string {
+	var sb strings.Builder
+	for _, e := range r.Entries() {
+		sb.WriteString("\t" + e.String() + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,54 @@
+package logtest_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/crearosoft/corelib/loggermanager"
+	"github.com/crearosoft/corelib/loggermanager/logtest"
+	"go.uber.org/zap/zapcore"
+)
+
+var moduleLogger = loggermanager.Named("mongodb")
+
+func TestSwap(t *testing.T) {
+	before := loggermanager.GetLogger()
+
+	t.Run("captures", func(t *testing.T) {
+		logs := logtest.Swap(t)
+
+		ctx := loggermanager.ContextWithRequestID(context.Background(), "req-1")
+		loggermanager.LogErrorCtx(ctx, "insert failed", loggermanager.Err(errors.New("dup key")))
+		moduleLogger.With("collection", "users").Warn("slow query", "millis", 1200)
+		moduleLogger.Debug("connected")
+
+		e := logs.AssertLogged(t, zapcore.ErrorLevel, "insert failed")
+		if e.Fields["request_id"] != "req-1" || e.Fields["error"] != "dup key" {
+			t.Errorf("fields = %v", e.Fields)
+		}
+		if !strings.HasSuffix(e.Caller, "logtest_test.go:23") {
+			t.Errorf("caller = %q", e.Caller)
+		}
+		slow := logs.Entries().Logger("mongodb").Field("millis", 1200).Field("collection", "users")
+		if len(slow) != 1 || slow[0].Level != zapcore.WarnLevel {
+			t.Errorf("slow = %v", slow)
+		}
+		logs.AssertCount(t, zapcore.DebugLevel, 3)
+		logs.AssertNotLogged(t, zapcore.ErrorLevel, "connected")
+
+		logs.Reset()
+		if logs.Len() != 0 {
+			t.Error("Reset kept entries")
+		}
+		moduleLogger.Info("after reset")
+		if got := logs.Entries().Messages(); len(got) != 1 || got[0] != "after reset" {
+			t.Errorf("messages = %v", got)
+		}
+	})
+
+	if loggermanager.GetLogger() != before {
+		t.Error("package logger not restored")
+	}
+}
@@ -0,0 +1,390 @@
+package loggermanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// Fields moved from the attributes of an OTel record to its resource
+var otelResourceKeys = map[string]string{
+	"service":     "service.name",
+	"version":     "service.version",
+	"environment": "deployment.environment",
+}
+
+var otelBuffers = buffer.NewPool()
+
+// otelRecord follows the OpenTelemetry log data model, timestamps are nanoseconds since the epoch
+type otelRecord struct {
+	Timestamp            string                 `json:"Timestamp"`
+	ObservedTimestamp    string                 `json:"ObservedTimestamp"`
+	TraceID              string                 `json:"TraceId,omitempty"`
+	SpanID               string                 `json:"SpanId,omitempty"`
+	SeverityText         string                 `json:"SeverityText"`
+	SeverityNumber       int                    `json:"SeverityNumber"`
+	Body                 string                 `json:"Body"`
+	Resource             map[string]interface{} `json:"Resource,omitempty"`
+	InstrumentationScope *otelScope             `json:"InstrumentationScope,omitempty"`
+	Attributes           map[string]interface{} `json:"Attributes,omitempty"`
+}
+
+type otelScope struct {
+	Name string `json:"Name"`
+}
+
+// otelEncoder writes one OTel log record per line
+type otelEncoder struct {
+	*zapcore.MapObjectEncoder
+	ns []string // open namespaces, outermost first
+}
+
+func newOTelEncoder() zapcore.Encoder {
+	return &otelEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder()}
+}
+
+func (e *otelEncoder) OpenNamespace(key string) {
+	e.MapObjectEncoder.OpenNamespace(key)
+	e.ns = append(e.ns, key)
+}
+
+// JSON has no NaN, infinities or complex numbers, they are written as strings like the JSON encoder does
+
+func (e *otelEncoder) AddFloat64(key string, f float64) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		e.AddString(key, strconv.FormatFloat(f, 'g', -1, 64))
+		return
+	}
+	e.MapObjectEncoder.AddFloat64(key, f)
+}
+
+func (e *otelEncoder) AddFloat32(key string, f float32) {
+	if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+		e.AddString(key, strconv.FormatFloat(float64(f), 'g', -1, 32))
+		return
+	}
+	e.MapObjectEncoder.AddFloat32(key, f)
+}
+
+func (e *otelEncoder) AddComplex128(key string, c complex128) {
+	e.AddString(key, strconv.FormatComplex(c, 'g', -1, 128))
+}
+
+func (e *otelEncoder) AddComplex64(key string, c complex64) {
+	e.AddString(key, strconv.FormatComplex(complex128(c), 'g', -1, 64))
+}
+
+// Clone copies the fields and opens the namespaces again, so later fields land in the innermost one.
+// The maps of open namespaces are copied, the other values are not changed after they were added.
+func (e *otelEncoder) Clone() zapcore.Encoder {
+	c := &otelEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), ns: make([]string, 0, len(e.ns))}
+	src, dst := e.Fields, c.Fields
+	for _, key := range e.ns {
+		for k, v := range src {
+			if k != key {
+				dst[k] = v
+			}
+		}
+		c.OpenNamespace(key)
+		src, _ = src[key].(map[string]interface{})
+		dst = dst[key].(map[string]interface{})
+	}
+	for k, v := range src {
+		dst[k] = v
+	}
+	return c
+}
+
+func (e *otelEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	enc := e.Clone().(*otelEncoder)
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	attrs := enc.Fields
+
+	rec := otelRecord{
+		Timestamp:         strconv.FormatInt(ent.Time.UnixNano(), 10),
+		ObservedTimestamp: strconv.FormatInt(time.Now().UnixNano(), 10),
+		SeverityText:      ent.Level.CapitalString(),
+		SeverityNumber:    otelSeverity(ent.Level),
+		Body:              ent.Message,
+	}
+	if ent.LoggerName != "" {
+		rec.InstrumentationScope = &otelScope{Name: ent.LoggerName}
+	}
+	if id, ok := attrs[FieldTraceID].(string); ok && validHex(id, 32) {
+		rec.TraceID = id
+		delete(attrs, FieldTraceID)
+	}
+	if id, ok := attrs[FieldSpanID].(string); ok && validHex(id, 16) {
+		rec.SpanID = id
+		delete(attrs, FieldSpanID)
+	}
+	for key, otelKey := range otelResourceKeys {
+		if v, ok := attrs[key]; ok {
+			if rec.Resource == nil {
+				rec.Resource = make(map[string]interface{}, len(otelResourceKeys))
+			}
+			rec.Resource[otelKey] = v
+			delete(attrs, key)
+		}
+	}
+	if v, ok := attrs["error"]; ok {
+		attrs["exception.message"] = v
+		delete(attrs, "error")
+	}
+	if v, ok := attrs["errorVerbose"]; ok {
+		attrs["exception.stacktrace"] = v
+		delete(attrs, "errorVerbose")
+	}
+	if ent.Stack != "" {
+		attrs["exception.stacktrace"] = ent.Stack
+	}
+	if ent.Caller.Defined {
+		attrs["code.filepath"] = ent.Caller.File
+		attrs["code.lineno"] = ent.Caller.Line
+		if ent.Caller.Function != "" {
+			attrs["code.function"] = ent.Caller.Function
+		}
+	}
+	if len(attrs) > 0 {
+		rec.Attributes = attrs
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		// e.g. NaN in an array or a reflected value, keep the record with those values as strings
+		stringifyUnsupported(rec.Resource)
+		stringifyUnsupported(rec.Attributes)
+		if b, err = json.Marshal(rec); err != nil {
+			return nil, err
+		}
+	}
+	buf := otelBuffers.Get()
+	buf.Write(b)
+	buf.AppendByte('\n')
+	return buf, nil
+}
+
+// stringifyUnsupported replaces the values of m which cannot be marshalled by their fmt form
+func stringifyUnsupported(m map[string]interface{}) {
+	for k, v := range m {
+		if _, err := json.Marshal(v); err != nil {
+			m[k] = fmt.Sprint(v)
+		}
+	}
+}
+
+// otelSeverity maps levels to the severity numbers of the OTel data model
+func otelSeverity(lvl zapcore.Level) int {
+	switch lvl {
+	case zapcore.DebugLevel:
+		return 5
+	case zapcore.InfoLevel:
+		return 9
+	case zapcore.WarnLevel:
+		return 13
+	case zapcore.ErrorLevel:
+		return 17
+	case zapcore.DPanicLevel:
+		return 18
+	case zapcore.PanicLevel:
+		return 21
+	case zapcore.FatalLevel:
+		return 22
+	}
+	if lvl < zapcore.DebugLevel {
+		return 1
+	}
+	return 24
+}
+
+func validHex(s string, n int) bool {
+	if len(s) != n || strings.Trim(s, "0") == "" {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// ContextWithTraceparent stores the trace and span ids of a W3C traceparent header,
+// e.g. "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", so logs join the trace.
+// ctx is returned unchanged if the header is invalid.
+func ContextWithTraceparent(ctx context.Context, traceparent string) (context.Context, bool) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || !validHex(parts[1], 32) || !validHex(parts[2], 16) {
+		return ctx, false
+	}
+	return ContextWithTrace(ctx, parts[1], parts[2]), true
+}
+
+// otlpBody converts the OTel records of an otlp sink batch into an OTLP/HTTP JSON export request
+func otlpBody(lines []byte) ([]byte, error) {
+	type scopeKey struct{ resource, scope string }
+	var (
+		resources []string
+		resAttrs  = make(map[string][]otlpKeyValue)
+		scopes    = make(map[string][]string)
+		records   = make(map[scopeKey][]otlpLogRecord)
+	)
+	for _, line := range bytes.Split(lines, []byte{'\n'}) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		var rec otelRecord
+		if err := dec.Decode(&rec); err != nil {
+			return nil, err
+		}
+		res, _ := json.Marshal(rec.Resource)
+		if _, ok := resAttrs[string(res)]; !ok {
+			resources = append(resources, string(res))
+			resAttrs[string(res)] = otlpAttributes(rec.Resource)
+		}
+		scope := ""
+		if rec.InstrumentationScope != nil {
+			scope = rec.InstrumentationScope.Name
+		}
+		key := scopeKey{string(res), scope}
+		if _, ok := records[key]; !ok {
+			scopes[string(res)] = append(scopes[string(res)], scope)
+		}
+		records[key] = append(records[key], otlpLogRecord{
+			TimeUnixNano:         rec.Timestamp,
+			ObservedTimeUnixNano: rec.ObservedTimestamp,
+			SeverityNumber:       rec.SeverityNumber,
+			SeverityText:         rec.SeverityText,
+			Body:                 otlpValue(rec.Body),
+			Attributes:           otlpAttributes(rec.Attributes),
+			TraceID:              rec.TraceID,
+			SpanID:               rec.SpanID,
+		})
+	}
+
+	req := otlpRequest{ResourceLogs: make([]otlpResourceLogs, 0, len(resources))}
+	for _, res := range resources {
+		rl := otlpResourceLogs{Resource: otlpResource{Attributes: resAttrs[res]}}
+		for _, scope := range scopes[res] {
+			rl.ScopeLogs = append(rl.ScopeLogs, otlpScopeLogs{
+				Scope:      otlpScope{Name: scope},
+				LogRecords: records[scopeKey{res, scope}],
+			})
+		}
+		req.ResourceLogs = append(req.ResourceLogs, rl)
+	}
+	return json.Marshal(req)
+}
+
+type otlpRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name,omitempty"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+// otlpAnyValue holds exactly one of its fields, 64 bit integers are strings in OTLP JSON
+type otlpAnyValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *string         `json:"intValue,omitempty"`
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+	KvlistValue *otlpKvList     `json:"kvlistValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpAnyValue `json:"values"`
+}
+
+type otlpKvList struct {
+	Values []otlpKeyValue `json:"values"`
+}
+
+func otlpAttributes(m map[string]interface{}) []otlpKeyValue {
+	if len(m) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]otlpKeyValue, len(keys))
+	for i, k := range keys {
+		out[i] = otlpKeyValue{Key: k, Value: otlpValue(m[k])}
+	}
+	return out
+}
+
+func otlpValue(v interface{}) otlpAnyValue {
+	switch t := v.(type) {
+	case nil:
+		return otlpAnyValue{}
+	case string:
+		return otlpAnyValue{StringValue: &t}
+	case bool:
+		return otlpAnyValue{BoolValue: &t}
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			s := t.String()
+			return otlpAnyValue{IntValue: &s}
+		}
+		f, _ := t.Float64()
+		return otlpAnyValue{DoubleValue: &f}
+	case float64:
+		return otlpAnyValue{DoubleValue: &t}
+	case []interface{}:
+		arr := &otlpArrayValue{Values: make([]otlpAnyValue, len(t))}
+		for i := range t {
+			arr.Values[i] = otlpValue(t[i])
+		}
+		return otlpAnyValue{ArrayValue: arr}
+	case map[string]interface{}:
+		return otlpAnyValue{KvlistValue: &otlpKvList{Values: otlpAttributes(t)}}
+	}
+	b, _ := json.Marshal(v)
+	s := string(b)
+	return otlpAnyValue{StringValue: &s}
+}
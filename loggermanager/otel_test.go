@@ -0,0 +1,198 @@
+package loggermanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+)
+
+func TestOTelEncoder(t *testing.T) {
+	enc := newOTelEncoder()
+	enc.AddString("service", "orders")
+	ent := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Unix(1700000000, 5),
+		LoggerName: "mongodb",
+		Message:    "slow query",
+		Caller:     zapcore.NewEntryCaller(0, "/src/dao.go", 42, true),
+	}
+	buf, err := enc.EncodeEntry(ent, []zapcore.Field{
+		zap.String(FieldTraceID, testTraceID),
+		zap.String(FieldSpanID, testSpanID),
+		zap.Int("millis", 900),
+		zap.Error(io.EOF),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec otelRecord
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Timestamp != "1700000000000000005" || rec.SeverityNumber != 13 || rec.SeverityText != "WARN" || rec.Body != "slow query" {
+		t.Errorf("record = %+v", rec)
+	}
+	if rec.TraceID != testTraceID || rec.SpanID != testSpanID || rec.InstrumentationScope.Name != "mongodb" {
+		t.Errorf("correlation = %+v", rec)
+	}
+	if rec.Resource["service.name"] != "orders" || rec.Attributes["service"] != nil {
+		t.Errorf("resource = %v, attributes = %v", rec.Resource, rec.Attributes)
+	}
+	if rec.Attributes["exception.message"] != "EOF" || rec.Attributes["code.lineno"] != float64(42) || rec.Attributes["millis"] != float64(900) {
+		t.Errorf("attributes = %v", rec.Attributes)
+	}
+}
+
+type otlpCollector struct {
+	mu   sync.Mutex
+	reqs []otlpRequest
+}
+
+func (c *otlpCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req otlpRequest
+	if r.URL.Path != "/v1/logs" || r.Header.Get("Content-Type") != "application/json" || json.NewDecoder(r.Body).Decode(&req) != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.reqs = append(c.reqs, req)
+	c.mu.Unlock()
+}
+
+func TestOTelEncoderNamespace(t *testing.T) {
+	var buf bytes.Buffer
+	l := zap.New(zapcore.NewCore(newOTelEncoder(), zapcore.AddSync(&buf), zapcore.DebugLevel)).
+		With(zap.Namespace("db"), zap.String("query", "find"))
+	l.With(zap.Int("shard", 2)).Info("slow", zap.Int("millis", 900))
+	l.Info("fast")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d records", len(lines))
+	}
+	want := []map[string]interface{}{
+		{"query": "find", "shard": float64(2), "millis": float64(900)},
+		{"query": "find"},
+	}
+	for i, line := range lines {
+		var rec otelRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatal(err)
+		}
+		db, _ := rec.Attributes["db"].(map[string]interface{})
+		if len(rec.Attributes) != 1 || len(db) != len(want[i]) {
+			t.Errorf("%s: attributes = %v", rec.Body, rec.Attributes)
+			continue
+		}
+		for k, v := range want[i] {
+			if db[k] != v {
+				t.Errorf("%s: attributes = %v", rec.Body, rec.Attributes)
+			}
+		}
+	}
+}
+
+func TestOTelEncoderUnsupportedValues(t *testing.T) {
+	var buf bytes.Buffer
+	l := zap.New(zapcore.NewCore(newOTelEncoder(), zapcore.AddSync(&buf), zapcore.DebugLevel))
+	l.Info("stats", zap.Float64("ratio", math.NaN()), zap.Float32("max", float32(math.Inf(1))),
+		zap.Complex128("z", complex(1, 2)), zap.Float64s("samples", []float64{1, math.Inf(-1)}), zap.Float64("mean", 0.5))
+
+	var rec otelRecord
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("record %q: %v", buf.String(), err)
+	}
+	want := map[string]interface{}{"ratio": "NaN", "max": "+Inf", "z": "(1+2i)", "samples": "[1 -Inf]", "mean": 0.5}
+	for k, v := range want {
+		if rec.Attributes[k] != v {
+			t.Errorf("%s = %v, want %v", k, rec.Attributes[k], v)
+		}
+	}
+}
+
+func TestOTLPSink(t *testing.T) {
+	col := &otlpCollector{}
+	srv := httptest.NewServer(col)
+	defer srv.Close()
+
+	prev := GetLogger()
+	defer SetLogger(prev)
+	defer Close()
+
+	err := InitFromConfig(Config{
+		Service: "orders",
+		Version: "1.2.3",
+		Sinks:   []SinkConfig{{Type: SinkOTLP, URL: srv.URL + "/v1/logs", FlushInterval: time.Hour}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, ok := ContextWithTraceparent(context.Background(), "00-"+testTraceID+"-"+testSpanID+"-01")
+	if !ok {
+		t.Fatal("traceparent rejected")
+	}
+	FromContext(ctx).Named("mongodb").Info("connected", "hosts", []string{"a", "b"}, "ratio", 0.5)
+	LogWarn("no scope")
+	if err := Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(col.reqs) != 1 || len(col.reqs[0].ResourceLogs) != 1 {
+		t.Fatalf("collector got %+v", col.reqs)
+	}
+	rl := col.reqs[0].ResourceLogs[0]
+	attrs := map[string]string{}
+	for _, kv := range rl.Resource.Attributes {
+		attrs[kv.Key] = *kv.Value.StringValue
+	}
+	if attrs["service.name"] != "orders" || attrs["service.version"] != "1.2.3" {
+		t.Errorf("resource = %v", attrs)
+	}
+	if len(rl.ScopeLogs) != 2 || rl.ScopeLogs[0].Scope.Name != "mongodb" {
+		t.Fatalf("scopes = %+v", rl.ScopeLogs)
+	}
+	rec := rl.ScopeLogs[0].LogRecords[0]
+	if rec.TraceID != testTraceID || rec.SpanID != testSpanID || *rec.Body.StringValue != "connected" || rec.SeverityNumber != 9 {
+		t.Errorf("record = %+v", rec)
+	}
+	for _, kv := range rec.Attributes {
+		switch kv.Key {
+		case "hosts":
+			if kv.Value.ArrayValue == nil || len(kv.Value.ArrayValue.Values) != 2 {
+				t.Errorf("hosts = %+v", kv.Value)
+			}
+		case "ratio":
+			if kv.Value.DoubleValue == nil || *kv.Value.DoubleValue != 0.5 {
+				t.Errorf("ratio = %+v", kv.Value)
+			}
+		case "code.lineno":
+			if kv.Value.IntValue == nil {
+				t.Errorf("code.lineno = %+v", kv.Value)
+			}
+		}
+	}
+}
+
+func TestContextWithTraceparent(t *testing.T) {
+	for _, h := range []string{"", "00-" + testTraceID, "00-" + strings.Repeat("0", 32) + "-" + testSpanID + "-01", "ff-" + testTraceID + "-" + testSpanID + "-01", "00-" + testTraceID + "-xyz-01"} {
+		if _, ok := ContextWithTraceparent(context.Background(), h); ok {
+			t.Errorf("accepted %q", h)
+		}
+	}
+}
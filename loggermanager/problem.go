@@ -0,0 +1,205 @@
+package loggermanager
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ProblemContentType is the media type of RFC 7807 responses
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object
+type Problem struct {
+	Type     string
+	Title    string
+	Status   int
+	Detail   string
+	Instance string
+	// Extensions are added as top level members next to the standard ones
+	Extensions map[string]interface{}
+}
+
+// MarshalJSON flattens Extensions into the problem object
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	m["type"] = p.Type
+	m["title"] = p.Title
+	m["status"] = p.Status
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+	return json.Marshal(m)
+}
+
+// ProblemOptions controls how errors are rendered
+type ProblemOptions struct {
+	// Production hides error messages, details and causes, users only see the catalog message.
+	// It is on until SetProblemOptions or InitFromConfig with an environment other than prod switches it off.
+	Production bool
+	// TypeBase is prefixed to the lower-cased code to build the problem type, default "urn:corelib:error:"
+	TypeBase string
+}
+
+// ErrorMapper turns a foreign error into a CoreError, it returns nil for errors it does not know
+type ErrorMapper func(err error) *CoreError
+
+var (
+	problemMu   sync.RWMutex
+	problemOpts = ProblemOptions{Production: true, TypeBase: "urn:corelib:error:"}
+	mappers     []*ErrorMapper
+)
+
+// SetProblemOptions replaces the options used by WriteProblem
+func SetProblemOptions(opts ProblemOptions) {
+	if opts.TypeBase == "" {
+		opts.TypeBase = "urn:corelib:error:"
+	}
+	problemMu.Lock()
+	problemOpts = opts
+	problemMu.Unlock()
+}
+
+// RegisterErrorMapper adds a mapper used for errors without a code, e.g. driver errors.
+// Calling the returned func removes it again.
+func RegisterErrorMapper(m ErrorMapper) (unregister func()) {
+	pm := &m
+	problemMu.Lock()
+	mappers = append(mappers[:len(mappers):len(mappers)], pm)
+	problemMu.Unlock()
+	return func() {
+		problemMu.Lock()
+		defer problemMu.Unlock()
+		// a new slice, Classify may be iterating the old one
+		ms := make([]*ErrorMapper, 0, len(mappers))
+		for _, o := range mappers {
+			if o != pm {
+				ms = append(ms, o)
+			}
+		}
+		mappers = ms
+	}
+}
+
+// Classify returns the CoreError describing err, using the registered mappers for errors without a code
+func Classify(err error) *CoreError {
+	var cerr *CoreError
+	if errors.As(err, &cerr) && cerr.code != CodeUnknown {
+		return cerr
+	}
+	problemMu.RLock()
+	ms := mappers
+	problemMu.RUnlock()
+	for _, m := range ms {
+		if mapped := (*m)(err); mapped != nil {
+			return mapped
+		}
+	}
+	if cerr != nil {
+		return cerr
+	}
+	return &CoreError{msg: err.Error(), cause: err}
+}
+
+// NewProblem builds the problem for err raised while serving r
+func NewProblem(r *http.Request, err error) *Problem {
+	problemMu.RLock()
+	opts := problemOpts
+	problemMu.RUnlock()
+
+	cerr := Classify(err)
+	status := HTTPStatus(cerr)
+	p := &Problem{
+		Type:       "about:blank",
+		Title:      http.StatusText(status),
+		Status:     status,
+		Instance:   r.URL.Path,
+		Extensions: map[string]interface{}{"correlation_id": correlationID(r)},
+	}
+	if cerr.code != CodeUnknown {
+		p.Type = opts.TypeBase + strings.ToLower(strings.ReplaceAll(string(cerr.code), "_", "-"))
+		p.Extensions["code"] = cerr.code
+	}
+	if opts.Production {
+		p.Detail = UserMessage(cerr, acceptLanguage(r))
+		return p
+	}
+	p.Detail = err.Error()
+	if len(cerr.details) > 0 {
+		p.Extensions["details"] = cerr.Details()
+	}
+	return p
+}
+
+// WriteProblem logs err with the correlation values of r and writes it as application/problem+json
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	p := NewProblem(r, err)
+
+	l := FromContext(r.Context())
+	kv := []interface{}{"status", p.Status, "correlation_id", p.Extensions["correlation_id"], "path", r.URL.Path, Err(err)}
+	if code, ok := p.Extensions["code"]; ok {
+		kv = append(kv, "code", code)
+	}
+	if p.Status >= http.StatusInternalServerError {
+		l.Error("request failed", kv...)
+	} else {
+		l.Warn("request failed", kv...)
+	}
+
+	writeProblem(w, p)
+}
+
+func writeProblem(w http.ResponseWriter, p *Problem) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// correlationID prefers the request id of the context, then the X-Request-ID header, else makes one up
+func correlationID(r *http.Request) string {
+	if id := RequestIDFromContext(r.Context()); id != "" {
+		return id
+	}
+	if id := r.Header.Get("X-Request-ID"); id != "" {
+		return id
+	}
+	return randomID()
+}
+
+// randomID returns 32 random hex digits
+func randomID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// acceptLanguage returns the first language of the Accept-Language header
+func acceptLanguage(r *http.Request) string {
+	lang := r.Header.Get("Accept-Language")
+	if i := strings.IndexAny(lang, ",;"); i >= 0 {
+		lang = lang[:i]
+	}
+	return strings.TrimSpace(lang)
+}
+
+// setProduction switches production mode on for production-like environment names and off
+// for the others. It is kept without an environment.
+func setProduction(env string) {
+	if env == "" {
+		return
+	}
+	env = strings.ToLower(env)
+	problemMu.Lock()
+	problemOpts.Production = env == "prod" || env == "production"
+	problemMu.Unlock()
+}
@@ -0,0 +1,129 @@
+package loggermanager
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serveProblem(t *testing.T, r *http.Request, err error) map[string]interface{} {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	WriteProblem(rec, r, err)
+	if ct := rec.Header().Get("Content-Type"); ct != ProblemContentType {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if int(body["status"].(float64)) != rec.Code {
+		t.Errorf("status %v does not match response code %d", body["status"], rec.Code)
+	}
+	return body
+}
+
+func TestWriteProblem(t *testing.T) {
+	logs := observe(t)
+	SetProblemOptions(ProblemOptions{})
+
+	r := httptest.NewRequest(http.MethodGet, "/hosts/h1", nil)
+	r = r.WithContext(ContextWithRequestID(r.Context(), "req-7"))
+	body := serveProblem(t, r, New(CodeNoHostFound, "NO_HOST_FOUND").WithDetail("host", "h1"))
+
+	want := map[string]interface{}{
+		"type":           "urn:corelib:error:no-host-found",
+		"title":          "Not Found",
+		"status":         float64(404),
+		"detail":         "NO_HOST_FOUND",
+		"instance":       "/hosts/h1",
+		"code":           "NO_HOST_FOUND",
+		"correlation_id": "req-7",
+	}
+	for k, v := range want {
+		if body[k] != v {
+			t.Errorf("%s = %v, want %v", k, body[k], v)
+		}
+	}
+	if d, _ := body["details"].(map[string]interface{}); d["host"] != "h1" {
+		t.Errorf("details = %v", body["details"])
+	}
+	entries := logs.FilterField(String("correlation_id", "req-7")).All()
+	if len(entries) != 1 || entries[0].Level.String() != "warn" {
+		t.Errorf("logged %v", entries)
+	}
+}
+
+func TestWriteProblemProduction(t *testing.T) {
+	logs := observe(t)
+	SetProblemOptions(ProblemOptions{Production: true, TypeBase: "https://errors.example.com/"})
+	t.Cleanup(func() { SetProblemOptions(ProblemOptions{}) })
+
+	r := httptest.NewRequest(http.MethodPost, "/files", nil)
+	r.Header.Set("Accept-Language", "en-GB,en;q=0.8")
+	internal := errors.New("dial tcp 10.0.0.3:27017: connection refused")
+	body := serveProblem(t, r, Wrapf(internal, CodeSessionNotFound, "Session not found for instance: h1").WithDetail("host", "h1"))
+
+	if body["detail"] != UserMessage(ErrSessionNotFound, "en") {
+		t.Errorf("detail = %v", body["detail"])
+	}
+	if body["type"] != "https://errors.example.com/session-not-found" {
+		t.Errorf("type = %v", body["type"])
+	}
+	if _, ok := body["details"]; ok {
+		t.Error("details are exposed in production")
+	}
+	id, _ := body["correlation_id"].(string)
+	if len(id) != 32 {
+		t.Errorf("correlation_id = %q", id)
+	}
+	entries := logs.FilterField(String("correlation_id", id)).All()
+	if len(entries) != 1 || entries[0].ContextMap()["error"] == nil {
+		t.Errorf("full error not logged: %v", entries)
+	}
+}
+
+func TestClassifyMapper(t *testing.T) {
+	errForeign := errors.New("foreign")
+	t.Cleanup(RegisterErrorMapper(func(err error) *CoreError {
+		if errors.Is(err, errForeign) {
+			return Wrapf(err, CodeInvalidArgument, "bad input")
+		}
+		return nil
+	}))
+
+	if got := Classify(Wrapf(errForeign, CodeUnknown, "wrapped")); got.Code() != CodeInvalidArgument {
+		t.Errorf("Classify = %q", got.Code())
+	}
+	if got := Classify(errors.New("other")); got.Code() != CodeUnknown || HTTPStatus(got) != http.StatusInternalServerError {
+		t.Errorf("Classify(other) = %q", got.Code())
+	}
+}
+
+func TestClassifyUnregisterMapper(t *testing.T) {
+	unregister := RegisterErrorMapper(func(err error) *CoreError { return New(CodeInvalidArgument, "mapped") })
+	unregister()
+	if got := Classify(errors.New("other")); got.Code() != CodeUnknown {
+		t.Errorf("unregistered mapper used: %q", got.Code())
+	}
+}
+
+func TestSetProduction(t *testing.T) {
+	t.Cleanup(func() { SetProblemOptions(ProblemOptions{}) })
+	production := func() bool {
+		problemMu.RLock()
+		defer problemMu.RUnlock()
+		return problemOpts.Production
+	}
+	for _, tt := range []struct {
+		env  string
+		want bool
+	}{{"Production", true}, {"staging", false}, {"", false}, {"prod", true}, {"", true}} {
+		setProduction(tt.env)
+		if production() != tt.want {
+			t.Errorf("after setProduction(%q) Production = %v", tt.env, !tt.want)
+		}
+	}
+}
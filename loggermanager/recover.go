@@ -0,0 +1,136 @@
+package loggermanager
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrorReporter forwards errors to an error tracking service
+type ErrorReporter interface {
+	Report(ctx context.Context, err error)
+}
+
+// ErrorReporterFunc adapts a function to ErrorReporter
+type ErrorReporterFunc func(ctx context.Context, err error)
+
+// Report calls f
+func (f ErrorReporterFunc) Report(ctx context.Context, err error) {
+	f(ctx, err)
+}
+
+var (
+	reporterMu sync.RWMutex
+	reporter   ErrorReporter
+)
+
+// SetErrorReporter sets the reporter of recovered panics, nil disables reporting
+func SetErrorReporter(r ErrorReporter) {
+	reporterMu.Lock()
+	reporter = r
+	reporterMu.Unlock()
+}
+
+// ReportError hands err to the error reporter, if any
+func ReportError(ctx context.Context, err error) {
+	reporterMu.RLock()
+	r := reporter
+	reporterMu.RUnlock()
+	if r != nil && err != nil {
+		r.Report(ctx, err)
+	}
+}
+
+// Recover logs and reports a panic instead of crashing, it must be deferred directly:
+//
+//	defer loggermanager.Recover(ctx)
+func Recover(ctx context.Context) {
+	if v := recover(); v != nil {
+		handlePanic(ctx, v)
+	}
+}
+
+// RecoverError is Recover which also stores the panic as a PANIC error in *errp
+//
+//	func work() (err error) {
+//		defer loggermanager.RecoverError(ctx, &err)
+func RecoverError(ctx context.Context, errp *error) {
+	if v := recover(); v != nil {
+		err := handlePanic(ctx, v)
+		if errp != nil {
+			*errp = err
+		}
+	}
+}
+
+// SafeGo runs fn in a goroutine which logs and reports panics instead of crashing the process
+func SafeGo(fn func()) {
+	go func() {
+		defer Recover(context.Background())
+		fn()
+	}()
+}
+
+// SafeGoContext is SafeGo for functions taking a context, panics are logged with its correlation values
+func SafeGoContext(ctx context.Context, fn func(ctx context.Context)) {
+	go func() {
+		defer Recover(ctx)
+		fn(ctx)
+	}()
+}
+
+// RecoverHandler answers requests whose handler panics with a 500 problem response.
+// If the handler started the response already, the panic is only logged and reported.
+// http.ErrAbortHandler is passed on as net/http expects.
+func RecoverHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			err := handlePanic(r.Context(), v)
+			if !sw.wroteHeader {
+				writeProblem(w, NewProblem(r, err))
+			}
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// recoveredPanic marks the log entry of a recovered panic, encoders skip it
+type recoveredPanic struct{}
+
+var recoveredPanicField = zapcore.Field{Type: zapcore.SkipType, Interface: recoveredPanic{}}
+
+// isRecoveredPanic reports whether f is the mark added by handlePanic
+func isRecoveredPanic(f zapcore.Field) bool {
+	_, ok := f.Interface.(recoveredPanic)
+	return ok && f.Type == zapcore.SkipType
+}
+
+// handlePanic must be called by the deferred function which recovered, so the stack still shows the panic
+func handlePanic(ctx context.Context, v interface{}) *CoreError {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(3, pcs)
+	err := &CoreError{code: CodePanic, msg: fmt.Sprint("panic: ", v), stack: pcs[:n], severity: SeverityCritical}
+	if cause, ok := v.(error); ok {
+		err.cause = cause
+		err.msg = "panic"
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	FromContext(ctx).Error("recovered from panic", "panic", fmt.Sprint(v), "stacktrace", string(debug.Stack()), recoveredPanicField)
+	ReportError(ctx, err)
+	return err
+}
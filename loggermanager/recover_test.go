@@ -0,0 +1,125 @@
+package loggermanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func swapReporter(t *testing.T) *[]error {
+	var (
+		mu       sync.Mutex
+		reported []error
+	)
+	SetErrorReporter(ErrorReporterFunc(func(_ context.Context, err error) {
+		mu.Lock()
+		reported = append(reported, err)
+		mu.Unlock()
+	}))
+	t.Cleanup(func() { SetErrorReporter(nil) })
+	return &reported
+}
+
+func panicky() {
+	panic("boom")
+}
+
+func TestRecoverError(t *testing.T) {
+	logs := observe(t)
+	reported := swapReporter(t)
+
+	work := func() (err error) {
+		defer RecoverError(ContextWithRequestID(context.Background(), "req-9"), &err)
+		panicky()
+		return nil
+	}
+	err := work()
+
+	if !errors.Is(err, ErrPanic) || err.Error() != "panic: boom" {
+		t.Fatalf("err = %v", err)
+	}
+	var cerr *CoreError
+	errors.As(err, &cerr)
+	if cerr.Severity() != SeverityCritical || !strings.Contains(stackFunctions(cerr), "loggermanager.panicky") {
+		t.Errorf("stack does not show the panic site:\n%+v", cerr)
+	}
+	entries := logs.FilterMessage("recovered from panic").FilterField(String("request_id", "req-9")).All()
+	if len(entries) != 1 || !strings.Contains(entries[0].ContextMap()["stacktrace"].(string), "panicky") {
+		t.Errorf("logged %v", entries)
+	}
+	if len(*reported) != 1 || (*reported)[0] != err {
+		t.Errorf("reported %v", *reported)
+	}
+}
+
+func stackFunctions(cerr *CoreError) string {
+	var sb strings.Builder
+	for _, f := range cerr.StackTrace() {
+		sb.WriteString(f.Function + "\n")
+	}
+	return sb.String()
+}
+
+func TestSafeGo(t *testing.T) {
+	observe(t)
+	reported := make(chan error, 1)
+	SetErrorReporter(ErrorReporterFunc(func(_ context.Context, err error) { reported <- err }))
+	t.Cleanup(func() { SetErrorReporter(nil) })
+
+	cause := errors.New("worker failed")
+	SafeGo(func() { panic(cause) })
+
+	if err := <-reported; !errors.Is(err, cause) || !errors.Is(err, ErrPanic) {
+		t.Errorf("reported %v", err)
+	}
+}
+
+func TestRecoverHandler(t *testing.T) {
+	logs := observe(t)
+	reported := swapReporter(t)
+
+	h := RecoverHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panicky()
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("Content-Type") != ProblemContentType {
+		t.Fatalf("response %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var body map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if body["code"] != string(CodePanic) || body["instance"] != "/orders" {
+		t.Errorf("body = %v", body)
+	}
+	if logs.FilterMessage("recovered from panic").Len() != 1 || len(*reported) != 1 {
+		t.Error("panic not logged and reported")
+	}
+
+	// a started response is left alone
+	rec = httptest.NewRecorder()
+	RecoverHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		panicky()
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if rec.Body.String() != "partial" || rec.Header().Get("Content-Type") == ProblemContentType {
+		t.Errorf("problem written after the response started: %q", rec.Body.String())
+	}
+	if logs.FilterMessage("recovered from panic").Len() != 2 {
+		t.Error("panic after the response started not logged")
+	}
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("ErrAbortHandler not passed on: %v", v)
+		}
+	}()
+	RecoverHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
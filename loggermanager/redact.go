@@ -0,0 +1,294 @@
+package loggermanager
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redactable is implemented by types which control how they are logged,
+// e.g. a config struct returning a copy of itself without the password.
+type Redactable interface {
+	Redacted() interface{}
+}
+
+// RedactedValue replaces redacted values
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactedKeys are masked unless replaced with SetRedactedKeys
+var DefaultRedactedKeys = []string{"password", "token", "authorization", "secret"}
+
+type redactPattern struct {
+	re      *regexp.Regexp
+	replace func(string) string
+}
+
+type redactor struct {
+	keys  []string // lower case, matched as substrings of field keys
+	extra []*regexp.Regexp
+}
+
+var (
+	redactMu sync.RWMutex
+	redacts  = newRedactor(DefaultRedactedKeys)
+)
+
+var (
+	jwtPattern    = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	bearerPattern = regexp.MustCompile(`(?i)(\bauthorization["']?\s*[:=]\s*["']?)?\b(bearer|basic)\s+([A-Za-z0-9._~+/=-]+)`)
+	cardPattern   = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	// issuer prefixes and lengths of Visa, Mastercard, Amex, Diners, Discover, JCB and UnionPay
+	cardIIN = regexp.MustCompile(`^(4\d{12}(\d{3}){0,2}|(5[1-5]|2[2-7])\d{14}|3[47]\d{13}|3(0[0-5]|[689]\d)\d{11}|6(011|5\d\d|4[4-9]\d)\d{12,15}|35\d{14,17}|62\d{14,17})$`)
+)
+
+// builtinPatterns are always applied, bearer first so the scheme is kept
+var builtinPatterns = []redactPattern{
+	{re: bearerPattern, replace: func(s string) string {
+		m := bearerPattern.FindStringSubmatch(s)
+		if m[1] == "" && !credentialLike(m[3]) {
+			// prose such as "basic setup"
+			return s
+		}
+		return strings.TrimSuffix(s, m[3]) + RedactedValue
+	}},
+	{re: jwtPattern, replace: func(string) string { return RedactedValue }},
+	{re: cardPattern, replace: func(s string) string {
+		if cardLike(s) && luhn(s) {
+			return RedactedValue
+		}
+		return s
+	}},
+}
+
+func newRedactor(keys []string) *redactor {
+	r := &redactor{}
+	for _, k := range keys {
+		r.keys = append(r.keys, strings.ToLower(k))
+	}
+	return r
+}
+
+func currentRedactor() *redactor {
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+	return redacts
+}
+
+// SetRedactedKeys replaces the field names whose values are masked. Names
+// match case-insensitively anywhere in a key, so "token" masks "access_token".
+func SetRedactedKeys(keys ...string) {
+	r := newRedactor(keys)
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	r.extra = redacts.extra
+	redacts = r
+}
+
+// AddRedactPattern masks every match of re in messages and string values
+func AddRedactPattern(re *regexp.Regexp) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	extra := make([]*regexp.Regexp, 0, len(redacts.extra)+1)
+	redacts = &redactor{keys: redacts.keys, extra: append(append(extra, redacts.extra...), re)}
+}
+
+// Redact masks tokens, bearer credentials and card numbers found in s.
+// Credentials outside an Authorization header need 16 characters with a digit or mixed case,
+// card numbers without separators a known issuer prefix.
+func Redact(s string) string {
+	return currentRedactor().redactString(s)
+}
+
+// RedactValue returns the redacted form of v if it implements Redactable
+func RedactValue(v interface{}) interface{} {
+	if rv, ok := v.(Redactable); ok {
+		return rv.Redacted()
+	}
+	return v
+}
+
+func (r *redactor) sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range r.keys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) redactString(s string) string {
+	for _, p := range builtinPatterns {
+		s = p.re.ReplaceAllStringFunc(s, p.replace)
+	}
+	for _, re := range r.extra {
+		s = re.ReplaceAllLiteralString(s, RedactedValue)
+	}
+	return s
+}
+
+func (r *redactor) redactFields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		nf, changed := r.redactField(f)
+		if !changed {
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, i, len(fields))
+			copy(out, fields[:i])
+		}
+		out = append(out, nf)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func (r *redactor) redactField(f zapcore.Field) (zapcore.Field, bool) {
+	if f.Type == zapcore.SkipType {
+		return f, false
+	}
+	if r.sensitive(f.Key) {
+		return zap.String(f.Key, RedactedValue), true
+	}
+	if rv, ok := f.Interface.(Redactable); ok {
+		return zap.Any(f.Key, rv.Redacted()), true
+	}
+	switch f.Type {
+	case zapcore.StringType:
+		if s := r.redactString(f.String); s != f.String {
+			return zap.String(f.Key, s), true
+		}
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			if s := r.redactString(err.Error()); s != err.Error() {
+				return zap.String(f.Key, s), true
+			}
+		}
+	case zapcore.ReflectType:
+		if v, changed := r.redactReflected(f.Interface); changed {
+			return zap.Any(f.Key, v), true
+		}
+	}
+	return f, false
+}
+
+// redactReflected masks sensitive keys at any depth of a value logged via reflection
+func (r *redactor) redactReflected(v interface{}) (interface{}, bool) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v, false
+	}
+	var generic interface{}
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return v, false
+	}
+	changed := false
+	out := r.walk(generic, &changed)
+	return out, changed
+}
+
+func (r *redactor) walk(v interface{}, changed *bool) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if r.sensitive(k) {
+				t[k] = RedactedValue
+				*changed = true
+				continue
+			}
+			t[k] = r.walk(val, changed)
+		}
+	case []interface{}:
+		for i := range t {
+			t[i] = r.walk(t[i], changed)
+		}
+	case string:
+		if s := r.redactString(t); s != t {
+			*changed = true
+			return s
+		}
+	}
+	return v
+}
+
+// credentialLike reports whether a token following bearer or basic looks like a credential rather than a word
+func credentialLike(s string) bool {
+	if len(s) < 16 {
+		return false
+	}
+	var digit, upper, lower bool
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digit = true
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		}
+	}
+	return digit || (upper && lower)
+}
+
+// cardLike reports whether digits matching cardPattern are written like a card number, with
+// separators or a known issuer prefix. IDs and timestamps of the same length are left alone.
+func cardLike(s string) bool {
+	if strings.ContainsAny(s, " -") {
+		return true
+	}
+	return cardIIN.MatchString(s)
+}
+
+// luhn reports whether the digits of s pass the Luhn checksum
+func luhn(s string) bool {
+	sum, double, n := 0, false, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// redactCore masks secrets before entries reach a backend. It writes
+// unconditionally once enabled, so it must wrap a leaf core.
+type redactCore struct {
+	zapcore.Core
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(currentRedactor().redactFields(fields))}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	r := currentRedactor()
+	ent.Message = r.redactString(ent.Message)
+	return c.Core.Write(ent, r.redactFields(fields))
+}
@@ -0,0 +1,255 @@
+package loggermanager
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingPolicy logs the first Initial entries with the same level, logger
+// name and message in every Tick, then every Thereafter-th one. A Thereafter
+// of 0 drops everything above Initial, i.e. a plain rate limit.
+type SamplingPolicy struct {
+	Initial    int           `json:"initial" yaml:"initial"`
+	Thereafter int           `json:"thereafter" yaml:"thereafter"`
+	Tick       time.Duration `json:"tick" yaml:"tick"` // default 1s
+}
+
+// SamplingConfig selects a policy per module, then per level, then Default.
+// Entries at DPanic and above are never sampled.
+type SamplingConfig struct {
+	Default *SamplingPolicy                  `json:"default" yaml:"default"`
+	Levels  map[zapcore.Level]SamplingPolicy `json:"levels" yaml:"levels"`
+	Modules map[string]SamplingPolicy        `json:"modules" yaml:"modules"`
+
+	// SummaryInterval is how often "suppressed K similar messages" entries are
+	// logged for dropped entries, default 1m. Negative disables the periodic summaries,
+	// the counts pending when sampling is replaced are still written.
+	SummaryInterval time.Duration `json:"summaryInterval" yaml:"summaryInterval"`
+}
+
+const (
+	defaultSamplingTick    = time.Second
+	defaultSummaryInterval = time.Minute
+	sampleCounterIdle      = time.Hour // counters without entries for this long are removed
+)
+
+// sampleCounter tracks one message key
+type sampleCounter struct {
+	core       zapcore.Core // core to check the summary with, without With fields
+	level      zapcore.Level
+	name       string
+	msg        string
+	start      time.Time
+	n          int
+	suppressed int64
+}
+
+type sampler struct {
+	cfg      SamplingConfig
+	mu       sync.Mutex
+	counters map[string]*sampleCounter
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+var (
+	samplerMu sync.RWMutex
+	curSample *sampler
+)
+
+// SetSampling enables sampling for all package loggers, nil disables it.
+// Pending summaries of the previous configuration are written first.
+func SetSampling(cfg *SamplingConfig) {
+	var s *sampler
+	if cfg != nil {
+		s = newSampler(*cfg)
+	}
+	samplerMu.Lock()
+	prev := curSample
+	curSample = s
+	samplerMu.Unlock()
+	if prev != nil {
+		prev.stop()
+	}
+}
+
+func currentSampler() *sampler {
+	samplerMu.RLock()
+	defer samplerMu.RUnlock()
+	return curSample
+}
+
+func newSampler(cfg SamplingConfig) *sampler {
+	s := &sampler{
+		cfg:      cfg,
+		counters: make(map[string]*sampleCounter),
+		done:     make(chan struct{}),
+	}
+	interval, summaries := cfg.SummaryInterval, cfg.SummaryInterval >= 0
+	if interval <= 0 {
+		interval = defaultSummaryInterval
+	}
+	s.wg.Add(1)
+	go s.run(interval, summaries)
+	return s
+}
+
+func (s *sampler) run(interval time.Duration, summaries bool) {
+	defer s.wg.Done()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if summaries {
+				s.summarize()
+			} else {
+				s.prune()
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// prune removes the counters of keys without entries for sampleCounterIdle, dropping their
+// suppressed counts. It bounds the counters when summaries are disabled.
+func (s *sampler) prune() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, c := range s.counters {
+		if now.Sub(c.start) > sampleCounterIdle {
+			delete(s.counters, key)
+		}
+	}
+}
+
+func (s *sampler) stop() {
+	close(s.done)
+	s.wg.Wait()
+	s.summarize()
+}
+
+// policy returns the policy for a logger name and level, modules matching by longest prefix
+func (s *sampler) policy(name string, lvl zapcore.Level) (SamplingPolicy, bool) {
+	for n := name; n != "" && len(s.cfg.Modules) > 0; {
+		if p, ok := s.cfg.Modules[n]; ok {
+			return p, true
+		}
+		i := strings.LastIndexByte(n, '.')
+		if i < 0 {
+			break
+		}
+		n = n[:i]
+	}
+	if p, ok := s.cfg.Levels[lvl]; ok {
+		return p, true
+	}
+	if s.cfg.Default != nil {
+		return *s.cfg.Default, true
+	}
+	return SamplingPolicy{}, false
+}
+
+func (s *sampler) allow(ent zapcore.Entry, core zapcore.Core) bool {
+	if ent.Level >= zapcore.DPanicLevel {
+		return true
+	}
+	p, ok := s.policy(ent.LoggerName, ent.Level)
+	if !ok {
+		return true
+	}
+	tick := p.Tick
+	if tick <= 0 {
+		tick = defaultSamplingTick
+	}
+	key := ent.Level.String() + "|" + ent.LoggerName + "|" + ent.Message
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.counters[key]
+	if !ok {
+		c = &sampleCounter{core: core, level: ent.Level, name: ent.LoggerName, msg: ent.Message, start: ent.Time}
+		s.counters[key] = c
+	}
+	if ent.Time.Sub(c.start) >= tick {
+		c.start, c.n = ent.Time, 0
+	}
+	c.n++
+	if c.n <= p.Initial {
+		return true
+	}
+	if p.Thereafter > 0 && (c.n-p.Initial)%p.Thereafter == 0 {
+		return true
+	}
+	c.suppressed++
+	return false
+}
+
+// summarize writes one entry per key which had entries dropped since the last call
+func (s *sampler) summarize() {
+	type summary struct {
+		c *sampleCounter
+		k int64
+	}
+	var out []summary
+	now := time.Now()
+
+	s.mu.Lock()
+	for key, c := range s.counters {
+		if c.suppressed > 0 {
+			out = append(out, summary{c: c, k: c.suppressed})
+			c.suppressed = 0
+			continue
+		}
+		if now.Sub(c.start) > sampleCounterIdle {
+			delete(s.counters, key)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, sm := range out {
+		ent := zapcore.Entry{
+			Level:      sm.c.level,
+			LoggerName: sm.c.name,
+			Time:       now,
+			Message:    fmt.Sprintf("suppressed %d similar messages", sm.k),
+		}
+		// the sinks and module levels may have changed since the entries were dropped
+		if ce := sm.c.core.Check(ent, nil); ce != nil {
+			ce.Write(zap.String("sampled_message", sm.c.msg), zap.Int64("suppressed", sm.k))
+		}
+	}
+}
+
+// samplingCore drops entries according to the sampling configuration set with SetSampling
+type samplingCore struct {
+	zapcore.Core
+	// base checks and writes the summaries, Core if nil. It has no With fields since summaries
+	// are not about one logger's entries, and applies the module levels but not the sampling.
+	base zapcore.Core
+}
+
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{Core: c.Core.With(fields), base: c.baseCore()}
+}
+
+func (c *samplingCore) baseCore() zapcore.Core {
+	if c.base != nil {
+		return c.base
+	}
+	return c.Core
+}
+
+func (c *samplingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if s := currentSampler(); s != nil && c.Enabled(ent.Level) && !s.allow(ent, c.baseCore()) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
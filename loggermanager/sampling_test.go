@@ -0,0 +1,119 @@
+package loggermanager
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSamplingInitialThereafter(t *testing.T) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(&samplingCore{Core: obs})
+
+	SetSampling(&SamplingConfig{
+		Levels:          map[zapcore.Level]SamplingPolicy{zapcore.ErrorLevel: {Initial: 2, Thereafter: 3, Tick: time.Minute}},
+		SummaryInterval: -1,
+	})
+	defer SetSampling(nil)
+
+	for i := 0; i < 10; i++ {
+		l.Error("cache miss")
+		l.Info("not sampled")
+	}
+
+	// first 2, then the 5th and 8th
+	if n := logs.FilterMessage("cache miss").Len(); n != 4 {
+		t.Errorf("got %d sampled entries, want 4", n)
+	}
+	if n := logs.FilterMessage("not sampled").Len(); n != 10 {
+		t.Errorf("got %d info entries, want 10", n)
+	}
+}
+
+func TestSamplingSummary(t *testing.T) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(&samplingCore{Core: obs}).Named("cachemanager")
+
+	SetSampling(&SamplingConfig{
+		Modules:         map[string]SamplingPolicy{"cachemanager": {Initial: 1, Tick: time.Minute}},
+		SummaryInterval: -1,
+	})
+	for i := 0; i < 5; i++ {
+		l.Warn("redis down")
+	}
+	// disabling writes the pending summary
+	SetSampling(nil)
+
+	summaries := logs.FilterMessage("suppressed 4 similar messages").All()
+	if len(summaries) != 1 {
+		t.Fatalf("got %d summaries, want 1: %v", len(summaries), logs.All())
+	}
+	fields := summaries[0].ContextMap()
+	if fields["sampled_message"] != "redis down" || fields["suppressed"] != int64(4) {
+		t.Errorf("unexpected summary fields %v", fields)
+	}
+	if summaries[0].LoggerName != "cachemanager" {
+		t.Errorf("summary logger = %q", summaries[0].LoggerName)
+	}
+}
+
+func TestSamplingSummaryWithoutWithFields(t *testing.T) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(&samplingCore{Core: obs})
+
+	SetSampling(&SamplingConfig{Default: &SamplingPolicy{Initial: 1, Tick: time.Minute}, SummaryInterval: -1})
+	l.With(zap.String("request_id", "r1")).Warn("redis down")
+	l.With(zap.String("request_id", "r2")).Warn("redis down")
+	SetSampling(nil)
+
+	summaries := logs.FilterMessage("suppressed 1 similar messages").All()
+	if len(summaries) != 1 {
+		t.Fatalf("got %d summaries: %v", len(summaries), logs.All())
+	}
+	if _, ok := summaries[0].ContextMap()["request_id"]; ok {
+		t.Errorf("summary carries the fields of a sampled entry: %v", summaries[0].ContextMap())
+	}
+}
+
+func TestSamplingSummaryLevels(t *testing.T) {
+	all, allLogs := observer.New(zapcore.DebugLevel)
+	errOnly, errLogs := observer.New(zapcore.ErrorLevel)
+	l := zap.New(wrapCore(all, errOnly))
+	t.Cleanup(func() { ResetLevel("mongodb") })
+
+	SetSampling(&SamplingConfig{Default: &SamplingPolicy{Initial: 1, Tick: time.Minute}, SummaryInterval: -1})
+	for i := 0; i < 3; i++ {
+		l.Named("cachemanager").Warn("redis down")
+		l.Named("mongodb").Warn("slow query")
+	}
+	// raised after the entries were dropped
+	SetLevel("mongodb", zapcore.ErrorLevel)
+	SetSampling(nil)
+
+	if n := allLogs.FilterMessage("suppressed 2 similar messages").Len(); n != 1 {
+		t.Errorf("got %d summaries, want 1 for cachemanager: %v", n, allLogs.All())
+	}
+	if errLogs.Len() != 0 {
+		t.Errorf("error sink got %v", errLogs.All())
+	}
+}
+
+func TestSamplerPrune(t *testing.T) {
+	s := newSampler(SamplingConfig{Default: &SamplingPolicy{Initial: 1}, SummaryInterval: -1})
+	defer s.stop()
+	obs, _ := observer.New(zapcore.DebugLevel)
+	now := time.Now()
+	s.allow(zapcore.Entry{Level: zapcore.InfoLevel, Message: "old", Time: now.Add(-2 * sampleCounterIdle)}, obs)
+	s.allow(zapcore.Entry{Level: zapcore.InfoLevel, Message: "old", Time: now.Add(-2 * sampleCounterIdle)}, obs)
+	s.allow(zapcore.Entry{Level: zapcore.InfoLevel, Message: "new", Time: now}, obs)
+
+	s.prune()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.counters) != 1 {
+		t.Errorf("%d counters left, want 1", len(s.counters))
+	}
+}
@@ -0,0 +1,628 @@
+package loggermanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultSentryBatchSize      = 10
+	defaultSentryFlushInterval  = 5 * time.Second
+	defaultSentryTimeout        = 5 * time.Second
+	defaultSentryQueueSize      = 100
+	defaultSentryMaxBreadcrumbs = 30
+)
+
+// SentryOptions configures a reporter speaking the Sentry envelope protocol
+type SentryOptions struct {
+	// DSN as shown by Sentry, e.g. https://<key>@sentry.example.com/<project>
+	DSN string
+
+	Service     string // sent as server_name
+	Environment string
+	Release     string
+	Tags        map[string]string // added to every event
+
+	Level           string        // log entries at this level and above become events, default error
+	BreadcrumbLevel string        // log entries at this level and above become breadcrumbs, default info
+	BatchSize       int           // queued events which trigger a flush, default 10
+	FlushInterval   time.Duration // default 5s
+	Timeout         time.Duration // per request, default 5s
+	QueueSize       int           // events beyond it are dropped, default 100
+	MaxBreadcrumbs  int           // recent log entries attached to events, default 30
+}
+
+// SentryReporter sends errors and recovered panics to a Sentry compatible endpoint.
+// Events are queued and sent in the background when BatchSize are queued or every FlushInterval.
+// An envelope carries a single event, so a flush still posts one request per event.
+type SentryReporter struct {
+	opts       SentryOptions
+	dsn        string
+	endpoint   string
+	auth       string
+	client     *http.Client
+	minLevel   zapcore.Level
+	crumbLevel zapcore.Level
+
+	mu      sync.Mutex
+	queue   []*sentryEvent
+	dropped uint64
+
+	crumbsMu sync.Mutex
+	crumbs   []sentryBreadcrumb
+	next     int // ring position once crumbs is full
+
+	sendMu     sync.Mutex
+	removeCore func()
+	kick       chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
+	wg         sync.WaitGroup
+}
+
+// NewSentryReporter returns a reporter, see InstallSentry to feed it with logs and panics
+func NewSentryReporter(opts SentryOptions) (*SentryReporter, error) {
+	u, err := url.Parse(opts.DSN)
+	if err != nil || u.User == nil || u.Host == "" {
+		return nil, New(CodeInvalidConfig, "invalid sentry dsn")
+	}
+	i := strings.LastIndex(u.Path, "/")
+	project := u.Path[i+1:]
+	if project == "" {
+		return nil, New(CodeInvalidConfig, "sentry dsn has no project id")
+	}
+	r := &SentryReporter{
+		opts:       opts,
+		dsn:        opts.DSN,
+		endpoint:   u.Scheme + "://" + u.Host + u.Path[:i] + "/api/" + project + "/envelope/",
+		auth:       "Sentry sentry_version=7, sentry_client=corelib/1.0, sentry_key=" + u.User.Username(),
+		client:     &http.Client{Timeout: opts.Timeout},
+		minLevel:   zapcore.ErrorLevel,
+		crumbLevel: zapcore.InfoLevel,
+		kick:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	if opts.Level != "" {
+		if r.minLevel, err = parseLevel(opts.Level); err != nil {
+			return nil, err
+		}
+	}
+	if opts.BreadcrumbLevel != "" {
+		if r.crumbLevel, err = parseLevel(opts.BreadcrumbLevel); err != nil {
+			return nil, err
+		}
+	}
+	if r.client.Timeout <= 0 {
+		r.client.Timeout = defaultSentryTimeout
+	}
+	if r.opts.BatchSize <= 0 {
+		r.opts.BatchSize = defaultSentryBatchSize
+	}
+	if r.opts.FlushInterval <= 0 {
+		r.opts.FlushInterval = defaultSentryFlushInterval
+	}
+	if r.opts.QueueSize <= 0 {
+		r.opts.QueueSize = defaultSentryQueueSize
+	}
+	if r.opts.MaxBreadcrumbs <= 0 {
+		r.opts.MaxBreadcrumbs = defaultSentryMaxBreadcrumbs
+	}
+	if r.opts.Service == "" {
+		r.opts.Service, _ = os.Hostname()
+	}
+	r.wg.Add(1)
+	go r.run()
+	return r, nil
+}
+
+// InstallSentry creates a reporter, makes it the error reporter and feeds it every log entry
+// for breadcrumbs and events. Close uninstalls it.
+func InstallSentry(opts SentryOptions) (*SentryReporter, error) {
+	r, err := NewSentryReporter(opts)
+	if err != nil {
+		return nil, err
+	}
+	SetErrorReporter(r)
+	r.removeCore = AddCore(&sentryCore{r: r})
+	return r, nil
+}
+
+// Report queues err as an event, with the correlation values of ctx as tags.
+// Errors other than CoreError carry no stack, they get the stack of the code reporting them.
+func (r *SentryReporter) Report(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	ev := r.newEvent("error", Redact(err.Error()))
+	ev.Logger = "reporter"
+	ev.addContext(ctx)
+
+	var cerr *CoreError
+	var frames []runtime.Frame
+	if errors.As(err, &cerr) {
+		ev.Level = cerr.Severity().sentryLevel()
+		for k, v := range cerr.Details() {
+			ev.extra()[k] = RedactValue(v)
+		}
+		frames = cerr.StackTrace()
+	} else {
+		frames = reportFrames(callers(2))
+	}
+	ev.setException(errorType(err), ev.Message, frames)
+	r.enqueue(ev)
+}
+
+// Flush sends the queued events now
+func (r *SentryReporter) Flush() error {
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+	r.mu.Lock()
+	queue := r.queue
+	r.queue = nil
+	r.mu.Unlock()
+
+	var err error
+	for _, ev := range queue {
+		if serr := r.send(ev); serr != nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+// Dropped returns the number of events dropped because the queue was full
+func (r *SentryReporter) Dropped() uint64 {
+	return atomic.LoadUint64(&r.dropped)
+}
+
+// Close uninstalls the reporter and sends the queued events
+func (r *SentryReporter) Close() error {
+	r.closeOnce.Do(func() {
+		if r.removeCore != nil {
+			r.removeCore()
+		}
+		reporterMu.Lock()
+		if reporter == ErrorReporter(r) {
+			reporter = nil
+		}
+		reporterMu.Unlock()
+		close(r.done)
+	})
+	r.wg.Wait()
+	return r.Flush()
+}
+
+func (r *SentryReporter) run() {
+	defer r.wg.Done()
+	t := time.NewTicker(r.opts.FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-r.kick:
+		case <-t.C:
+		case <-r.done:
+			return
+		}
+		r.Flush()
+	}
+}
+
+func (r *SentryReporter) enqueue(ev *sentryEvent) {
+	ev.Breadcrumbs = r.breadcrumbs()
+	r.mu.Lock()
+	if len(r.queue) >= r.opts.QueueSize {
+		r.mu.Unlock()
+		atomic.AddUint64(&r.dropped, 1)
+		return
+	}
+	r.queue = append(r.queue, ev)
+	full := len(r.queue) >= r.opts.BatchSize
+	r.mu.Unlock()
+	if full {
+		select {
+		case r.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// send posts one envelope holding ev
+func (r *SentryReporter) send(ev *sentryEvent) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	header, _ := json.Marshal(map[string]string{
+		"event_id": ev.EventID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339Nano),
+		"dsn":      r.dsn,
+	})
+	body.Write(header)
+	body.WriteString("\n{\"type\":\"event\",\"length\":" + strconv.Itoa(len(payload)) + "}\n")
+	body.Write(payload)
+	body.WriteByte('\n')
+
+	req, err := http.NewRequest(http.MethodPost, r.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return Wrapf(err, CodeLogShippingFailed, "sending event to sentry")
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return New(CodeLogShippingFailed, "sentry returned status "+strconv.Itoa(resp.StatusCode))
+	}
+	return nil
+}
+
+func (r *SentryReporter) newEvent(level, message string) *sentryEvent {
+	ev := &sentryEvent{
+		EventID:     randomID(),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339Nano),
+		Platform:    "go",
+		Level:       level,
+		Message:     message,
+		ServerName:  r.opts.Service,
+		Release:     r.opts.Release,
+		Environment: r.opts.Environment,
+		Tags:        make(map[string]string, len(r.opts.Tags)+2),
+	}
+	for k, v := range r.opts.Tags {
+		ev.Tags[k] = v
+	}
+	if r.opts.Service != "" {
+		ev.Tags["service"] = r.opts.Service
+	}
+	return ev
+}
+
+// logEvent turns a log entry into an event
+func (r *SentryReporter) logEvent(ent zapcore.Entry, fields map[string]interface{}, errs []error) *sentryEvent {
+	ev := r.newEvent(levelName(ent.Level), ent.Message)
+	ev.Logger = ent.LoggerName
+	ev.addFields(fields)
+
+	typ, value, frames := "log", ent.Message, []runtime.Frame(nil)
+	if len(errs) > 0 {
+		typ, value = errorType(errs[0]), errs[0].Error()
+		var cerr *CoreError
+		if errors.As(errs[0], &cerr) {
+			frames = cerr.StackTrace()
+		}
+	}
+	if frames == nil {
+		frames = callersFrom(ent.Caller)
+	}
+	ev.setException(typ, value, frames)
+	ev.Fingerprint = []string{typ, ent.LoggerName, ent.Message}
+	return ev
+}
+
+func (r *SentryReporter) addBreadcrumb(ent zapcore.Entry, fields map[string]interface{}) {
+	bc := sentryBreadcrumb{
+		Timestamp: ent.Time.UTC().Format(time.RFC3339Nano),
+		Category:  ent.LoggerName,
+		Level:     levelName(ent.Level),
+		Message:   ent.Message,
+		Data:      fields,
+	}
+	if bc.Category == "" {
+		bc.Category = "log"
+	}
+	r.crumbsMu.Lock()
+	defer r.crumbsMu.Unlock()
+	if len(r.crumbs) < r.opts.MaxBreadcrumbs {
+		r.crumbs = append(r.crumbs, bc)
+		return
+	}
+	r.crumbs[r.next] = bc
+	r.next = (r.next + 1) % len(r.crumbs)
+}
+
+// breadcrumbs returns the recent entries, oldest first
+func (r *SentryReporter) breadcrumbs() *sentryBreadcrumbs {
+	r.crumbsMu.Lock()
+	defer r.crumbsMu.Unlock()
+	if len(r.crumbs) == 0 {
+		return nil
+	}
+	out := make([]sentryBreadcrumb, 0, len(r.crumbs))
+	out = append(out, r.crumbs[r.next:]...)
+	out = append(out, r.crumbs[:r.next]...)
+	return &sentryBreadcrumbs{Values: out}
+}
+
+// sentryCore turns log entries into breadcrumbs and events
+type sentryCore struct {
+	r      *SentryReporter
+	fields []zapcore.Field
+}
+
+func (c *sentryCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= c.r.crumbLevel || lvl >= c.r.minLevel
+}
+
+func (c *sentryCore) With(fields []zapcore.Field) zapcore.Core {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	return &sentryCore{r: c.r, fields: append(append(all, c.fields...), fields...)}
+}
+
+func (c *sentryCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *sentryCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	var errs []error
+	for _, f := range append(c.fields[:len(c.fields):len(c.fields)], fields...) {
+		// recovered panics are reported by Recover with the stack of the panic
+		if isRecoveredPanic(f) {
+			return nil
+		}
+		if f.Type == zapcore.ErrorType {
+			if err, ok := f.Interface.(error); ok {
+				errs = append(errs, err)
+			}
+		}
+		f.AddTo(enc)
+	}
+	delete(enc.Fields, "errorVerbose")
+
+	if ent.Level >= c.r.minLevel {
+		c.r.enqueue(c.r.logEvent(ent, enc.Fields, errs))
+	}
+	if ent.Level >= c.r.crumbLevel {
+		c.r.addBreadcrumb(ent, enc.Fields)
+	}
+	return nil
+}
+
+func (c *sentryCore) Sync() error {
+	return c.r.Flush()
+}
+
+type sentryEvent struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   string                 `json:"timestamp"`
+	Platform    string                 `json:"platform"`
+	Level       string                 `json:"level"`
+	Logger      string                 `json:"logger,omitempty"`
+	Message     string                 `json:"message,omitempty"`
+	ServerName  string                 `json:"server_name,omitempty"`
+	Release     string                 `json:"release,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+	User        *sentryUser            `json:"user,omitempty"`
+	Fingerprint []string               `json:"fingerprint,omitempty"`
+	Exception   *sentryExceptions      `json:"exception,omitempty"`
+	Breadcrumbs *sentryBreadcrumbs     `json:"breadcrumbs,omitempty"`
+}
+
+type sentryUser struct {
+	ID string `json:"id"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+type sentryBreadcrumbs struct {
+	Values []sentryBreadcrumb `json:"values"`
+}
+
+type sentryBreadcrumb struct {
+	Timestamp string                 `json:"timestamp"`
+	Category  string                 `json:"category"`
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+func (ev *sentryEvent) extra() map[string]interface{} {
+	if ev.Extra == nil {
+		ev.Extra = make(map[string]interface{})
+	}
+	return ev.Extra
+}
+
+// addContext tags ev with the correlation values of ctx
+func (ev *sentryEvent) addContext(ctx context.Context) {
+	if ctx == nil {
+		return
+	}
+	kv := ContextFields(ctx)
+	fields := make(map[string]interface{}, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		if k, ok := kv[i].(string); ok {
+			fields[k] = kv[i+1]
+		}
+	}
+	ev.addFields(fields)
+}
+
+// addFields promotes correlation fields to tags and the user, everything else goes to extra
+func (ev *sentryEvent) addFields(fields map[string]interface{}) {
+	for k, v := range fields {
+		switch k {
+		case FieldRequestID, FieldTraceID, FieldSpanID, FieldTenant:
+			ev.Tags[k] = fmt.Sprint(v)
+		case FieldUser:
+			ev.User = &sentryUser{ID: fmt.Sprint(v)}
+		default:
+			ev.extra()[k] = v
+		}
+	}
+}
+
+func (ev *sentryEvent) setException(typ, value string, frames []runtime.Frame) {
+	exc := sentryException{Type: typ, Value: value}
+	if len(frames) > 0 {
+		st := &sentryStacktrace{Frames: make([]sentryFrame, 0, len(frames))}
+		for i := len(frames) - 1; i >= 0; i-- { // sentry lists the innermost frame last
+			st.Frames = append(st.Frames, newSentryFrame(frames[i]))
+		}
+		exc.Stacktrace = st
+		if ev.Fingerprint == nil {
+			ev.Fingerprint = []string{typ, culprit(frames)}
+		}
+	}
+	ev.Exception = &sentryExceptions{Values: []sentryException{exc}}
+}
+
+func newSentryFrame(f runtime.Frame) sentryFrame {
+	module, function := splitFunction(f.Function)
+	i := strings.LastIndexAny(f.File, `/\`)
+	return sentryFrame{
+		Function: function,
+		Module:   module,
+		Filename: f.File[i+1:],
+		AbsPath:  f.File,
+		Lineno:   f.Line,
+		InApp:    inApp(f.Function),
+	}
+}
+
+// splitFunction splits "github.com/a/b.(*T).M" into "github.com/a/b" and "(*T).M"
+func splitFunction(name string) (module, function string) {
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		return name[:slash+1+dot], name[slash+2+dot:]
+	}
+	return "", name
+}
+
+func inApp(function string) bool {
+	return function != "" && !strings.HasPrefix(function, "runtime.") && !strings.HasPrefix(function, "go.uber.org/")
+}
+
+// culprit is the innermost frame of application code
+func culprit(frames []runtime.Frame) string {
+	for _, f := range frames {
+		if inApp(f.Function) {
+			return f.Function
+		}
+	}
+	return ""
+}
+
+func errorType(err error) string {
+	if code := CodeOf(err); code != CodeUnknown {
+		return string(code)
+	}
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			return fmt.Sprintf("%T", err)
+		}
+		err = next
+	}
+}
+
+func callers(skip int) []runtime.Frame {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var out []runtime.Frame
+	for {
+		f, more := frames.Next()
+		out = append(out, f)
+		if !more {
+			return out
+		}
+	}
+}
+
+// reportFrames drops the frames of ReportError and ErrorReporterFunc from the stack of Report
+func reportFrames(frames []runtime.Frame) []runtime.Frame {
+	for len(frames) > 1 {
+		_, function := splitFunction(frames[0].Function)
+		if !strings.HasSuffix(frames[0].Function, "/loggermanager."+function) ||
+			(function != "ReportError" && function != "ErrorReporterFunc.Report") {
+			break
+		}
+		frames = frames[1:]
+	}
+	return frames
+}
+
+// callersFrom returns the stack starting at the caller of the log entry
+func callersFrom(caller zapcore.EntryCaller) []runtime.Frame {
+	frames := callers(2)
+	if !caller.Defined {
+		return frames
+	}
+	for i, f := range frames {
+		if f.File == caller.File && f.Line == caller.Line {
+			return frames[i:]
+		}
+	}
+	return frames
+}
+
+func (s Severity) sentryLevel() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "fatal"
+	}
+	return "error"
+}
+
+func levelName(lvl zapcore.Level) string {
+	switch {
+	case lvl <= zapcore.DebugLevel:
+		return "debug"
+	case lvl == zapcore.InfoLevel:
+		return "info"
+	case lvl == zapcore.WarnLevel:
+		return "warning"
+	case lvl == zapcore.ErrorLevel:
+		return "error"
+	}
+	return "fatal"
+}
@@ -0,0 +1,203 @@
+package loggermanager
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// sentryStandIn collects the events of posted envelopes
+type sentryStandIn struct {
+	mu     sync.Mutex
+	auth   []string
+	events []sentryEvent
+}
+
+func (s *sentryStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/42/envelope/" {
+		http.NotFound(w, r)
+		return
+	}
+	sc := bufio.NewScanner(r.Body)
+	sc.Buffer(nil, 1<<20)
+	var lines []string
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	var header map[string]string
+	var item struct {
+		Type   string
+		Length int
+	}
+	var ev sentryEvent
+	if len(lines) != 3 || json.Unmarshal([]byte(lines[0]), &header) != nil || json.Unmarshal([]byte(lines[1]), &item) != nil ||
+		item.Type != "event" || item.Length != len(lines[2]) || json.Unmarshal([]byte(lines[2]), &ev) != nil || header["event_id"] != ev.EventID {
+		http.Error(w, "bad envelope", http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.auth = append(s.auth, r.Header.Get("X-Sentry-Auth"))
+	s.events = append(s.events, ev)
+	s.mu.Unlock()
+}
+
+func installSentry(t *testing.T, opts SentryOptions) (*SentryReporter, *sentryStandIn) {
+	stand := &sentryStandIn{}
+	srv := httptest.NewServer(stand)
+	t.Cleanup(srv.Close)
+
+	prev := GetLogger()
+	SetLogger(NewZapLogger(zap.New(wrapCore(zapcore.NewNopCore()), zap.AddCaller())))
+	t.Cleanup(func() { SetLogger(prev) })
+
+	opts.DSN = strings.Replace(srv.URL, "://", "://public@", 1) + "/42"
+	r, err := InstallSentry(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r, stand
+}
+
+func TestSentryLogEvents(t *testing.T) {
+	r, stand := installSentry(t, SentryOptions{Service: "orders", Environment: "staging", Release: "1.2.3", Tags: map[string]string{"team": "core"}})
+
+	ctx := ContextWithUser(ContextWithRequestID(context.Background(), "req-5"), "alice")
+	LogInfo("loading order")
+	FromContext(ctx).Warn("slow query", "millis", 900)
+	LogErrorCtx(ctx, "order failed", Err(New(CodeNoHostFound, "NO_HOST_FOUND")))
+	if err := r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stand.events) != 1 {
+		t.Fatalf("sent %d events", len(stand.events))
+	}
+	ev := stand.events[0]
+	if !strings.Contains(stand.auth[0], "sentry_key=public") {
+		t.Errorf("auth = %q", stand.auth[0])
+	}
+	if ev.Level != "error" || ev.Message != "order failed" || ev.ServerName != "orders" || ev.Environment != "staging" || ev.Release != "1.2.3" {
+		t.Errorf("event = %+v", ev)
+	}
+	if ev.Tags["team"] != "core" || ev.Tags["request_id"] != "req-5" || ev.User == nil || ev.User.ID != "alice" {
+		t.Errorf("tags = %v, user = %v", ev.Tags, ev.User)
+	}
+	if len(ev.Fingerprint) == 0 || ev.Fingerprint[0] != string(CodeNoHostFound) {
+		t.Errorf("fingerprint = %v", ev.Fingerprint)
+	}
+	exc := ev.Exception.Values[0]
+	frames := exc.Stacktrace.Frames
+	if exc.Type != string(CodeNoHostFound) || frames[len(frames)-1].Function != "TestSentryLogEvents" {
+		t.Errorf("exception = %+v", exc)
+	}
+	crumbs := ev.Breadcrumbs.Values
+	if len(crumbs) != 2 || crumbs[0].Message != "loading order" || crumbs[1].Level != "warning" {
+		t.Errorf("breadcrumbs = %+v", crumbs)
+	}
+}
+
+func TestSentryReportsPanics(t *testing.T) {
+	r, stand := installSentry(t, SentryOptions{MaxBreadcrumbs: 1})
+
+	LogInfo("first")
+	LogInfo("second")
+	func() {
+		defer Recover(context.Background())
+		panicky()
+	}()
+	r.Close()
+
+	if len(stand.events) != 1 {
+		t.Fatalf("sent %d events, want only the report of the panic", len(stand.events))
+	}
+	ev := stand.events[0]
+	if ev.Level != "fatal" || ev.Exception.Values[0].Type != string(CodePanic) || ev.Fingerprint[1] != "github.com/crearosoft/corelib/loggermanager.panicky" {
+		t.Errorf("event = %+v", ev)
+	}
+	if crumbs := ev.Breadcrumbs.Values; len(crumbs) != 1 || crumbs[0].Message != "second" {
+		t.Errorf("breadcrumbs = %+v", crumbs)
+	}
+
+	ReportError(context.Background(), io.EOF)
+	if err := r.Flush(); err != nil || len(stand.events) != 1 {
+		t.Error("closed reporter is still installed")
+	}
+}
+
+func TestSentryPanicField(t *testing.T) {
+	r, stand := installSentry(t, SentryOptions{})
+
+	LogErrorw("worker stopped", "panic", false)
+	if err := r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(stand.events) != 1 || stand.events[0].Message != "worker stopped" {
+		t.Errorf("events = %+v", stand.events)
+	}
+}
+
+func TestSentryDSN(t *testing.T) {
+	for _, dsn := range []string{"", "https://sentry.example.com/1", "https://key@sentry.example.com/"} {
+		if _, err := NewSentryReporter(SentryOptions{DSN: dsn}); err == nil {
+			t.Errorf("dsn %q accepted", dsn)
+		}
+	}
+	r, err := NewSentryReporter(SentryOptions{DSN: "https://key@sentry.example.com/prefix/7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.endpoint != "https://sentry.example.com/prefix/api/7/envelope/" {
+		t.Errorf("endpoint = %q", r.endpoint)
+	}
+}
+
+func TestSentryBreadcrumbLevel(t *testing.T) {
+	r, stand := installSentry(t, SentryOptions{BreadcrumbLevel: "warn"})
+
+	c := &sentryCore{r: r}
+	if c.Enabled(zapcore.DebugLevel) || c.Enabled(zapcore.InfoLevel) || !c.Enabled(zapcore.WarnLevel) {
+		t.Error("core enabled below the breadcrumb level")
+	}
+	LogInfo("not a breadcrumb")
+	LogWarn("retrying")
+	LogError("failed")
+	if err := r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(stand.events) != 1 {
+		t.Fatalf("sent %d events", len(stand.events))
+	}
+	if crumbs := stand.events[0].Breadcrumbs.Values; len(crumbs) != 1 || crumbs[0].Message != "retrying" {
+		t.Errorf("breadcrumbs = %+v", crumbs)
+	}
+}
+
+func TestSentryReportStack(t *testing.T) {
+	r, stand := installSentry(t, SentryOptions{})
+
+	r.Report(context.Background(), io.EOF)
+	ReportError(context.Background(), io.ErrUnexpectedEOF)
+	if err := r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(stand.events) != 2 {
+		t.Fatalf("sent %d events", len(stand.events))
+	}
+	for _, ev := range stand.events {
+		frames := ev.Exception.Values[0].Stacktrace.Frames
+		if f := frames[len(frames)-1]; f.Function != "TestSentryReportStack" {
+			t.Errorf("%s: innermost frame %s", ev.Message, f.Function)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package loggermanager
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultBufferSize = 1024
+
+type asyncEntry struct {
+	lvl zapcore.Level
+	p   []byte
+}
+
+// asyncWriter moves writes of a sink to a background goroutine through a bounded buffer
+type asyncWriter struct {
+	out     sinkWriter
+	ch      chan asyncEntry
+	flush   chan chan error
+	done    chan struct{}
+	block   bool
+	dropped uint64
+
+	mu     sync.RWMutex // held for reading while sending, so nothing is sent after the last drain
+	closed bool
+
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func newAsyncWriter(out sinkWriter, size int, block bool) *asyncWriter {
+	if size <= 0 {
+		size = defaultBufferSize
+	}
+	a := &asyncWriter{
+		out:   out,
+		ch:    make(chan asyncEntry, size),
+		flush: make(chan chan error),
+		done:  make(chan struct{}),
+		block: block,
+	}
+	a.wg.Add(1)
+	go a.run()
+	return a
+}
+
+func (a *asyncWriter) run() {
+	defer a.wg.Done()
+	for {
+		select {
+		case e := <-a.ch:
+			a.out.WriteLevel(e.lvl, e.p)
+		case ack := <-a.flush:
+			a.drain()
+			ack <- a.out.Sync()
+		case <-a.done:
+			a.drain()
+			return
+		}
+	}
+}
+
+func (a *asyncWriter) drain() {
+	for {
+		select {
+		case e := <-a.ch:
+			a.out.WriteLevel(e.lvl, e.p)
+		default:
+			return
+		}
+	}
+}
+
+// WriteLevel copies p since the encoder reuses its buffer. Entries written after Close are dropped.
+func (a *asyncWriter) WriteLevel(lvl zapcore.Level, p []byte) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		atomic.AddUint64(&a.dropped, 1)
+		return nil
+	}
+	e := asyncEntry{lvl: lvl, p: append([]byte(nil), p...)}
+	if a.block {
+		// run drains the buffer until Close, which waits for this send
+		a.ch <- e
+		return nil
+	}
+	select {
+	case a.ch <- e:
+	default:
+		atomic.AddUint64(&a.dropped, 1)
+	}
+	return nil
+}
+
+// Sync waits until the buffer is written out
+func (a *asyncWriter) Sync() error {
+	ack := make(chan error, 1)
+	select {
+	case a.flush <- ack:
+		return <-ack
+	case <-a.done:
+		return nil
+	}
+}
+
+func (a *asyncWriter) Close() error {
+	a.closeOnce.Do(func() {
+		a.mu.Lock()
+		a.closed = true
+		a.mu.Unlock()
+		close(a.done)
+		a.wg.Wait()
+	})
+	return a.out.Close()
+}
+
+// Dropped returns the number of entries dropped because the buffer was full or the writer closed
+func (a *asyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
@@ -0,0 +1,186 @@
+package loggermanager
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultHTTPBatchSize     = 100
+	defaultHTTPFlushInterval = time.Second
+	defaultHTTPTimeout       = 5 * time.Second
+	// httpRetainBatches is the number of batches kept for retry while the collector fails
+	httpRetainBatches = 10
+)
+
+// httpWriter ships batches of JSON lines to a collector
+type httpWriter struct {
+	url         string
+	headers     map[string]string
+	contentType string
+	encode      func(batch []byte) ([]byte, error) // turns the JSON lines into the request body, nil sends them as is
+	client      *http.Client
+	batchSize   int
+
+	mu      sync.Mutex // guards buf, n and lastErr, not held while posting
+	buf     bytes.Buffer
+	n       int
+	lastErr error
+	sendMu  sync.Mutex // one post at a time, so retried entries keep their order
+	dropped uint64
+
+	kick      chan struct{} // a full batch wakes run, posts never block the logging goroutine
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func newHTTPWriter(cfg SinkConfig) *httpWriter {
+	w := &httpWriter{
+		url:         cfg.URL,
+		headers:     cfg.Headers,
+		contentType: "application/x-ndjson",
+		client:      &http.Client{Timeout: cfg.Timeout},
+		batchSize:   cfg.BatchSize,
+		kick:        make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+	if w.client.Timeout <= 0 {
+		w.client.Timeout = defaultHTTPTimeout
+	}
+	if w.batchSize <= 0 {
+		w.batchSize = defaultHTTPBatchSize
+	}
+	interval := cfg.FlushInterval
+	if interval <= 0 {
+		interval = defaultHTTPFlushInterval
+	}
+	w.wg.Add(1)
+	go w.run(interval)
+	return w
+}
+
+func (w *httpWriter) run(interval time.Duration) {
+	defer w.wg.Done()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	var failing bool
+	for {
+		select {
+		case <-t.C:
+		case <-w.kick:
+			if failing {
+				// retried entries keep the buffer full, wait for the tick instead of posting on every write
+				continue
+			}
+		case <-w.done:
+			return
+		}
+		failing = w.Sync() != nil
+	}
+}
+
+// WriteLevel buffers p and wakes the background goroutine when a batch is full.
+// Entries beyond httpRetainBatches batches are dropped while the collector fails.
+func (w *httpWriter) WriteLevel(_ zapcore.Level, p []byte) error {
+	w.mu.Lock()
+	if w.n >= httpRetainBatches*w.batchSize {
+		w.mu.Unlock()
+		atomic.AddUint64(&w.dropped, 1)
+		return nil
+	}
+	w.buf.Write(p)
+	w.n++
+	full := w.n >= w.batchSize
+	w.mu.Unlock()
+	if full {
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Sync posts the pending entries and waits for the collector. Entries of a failed post are retried by the next Sync,
+// beyond httpRetainBatches batches they are dropped.
+func (w *httpWriter) Sync() error {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+
+	w.mu.Lock()
+	if w.n == 0 {
+		err := w.lastErr
+		w.mu.Unlock()
+		return err
+	}
+	body, n := w.buf.Bytes(), w.n
+	w.buf = bytes.Buffer{}
+	w.n = 0
+	w.mu.Unlock()
+
+	err := w.post(body)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastErr = err
+	if err == nil {
+		return nil
+	}
+	if w.n+n > httpRetainBatches*w.batchSize {
+		atomic.AddUint64(&w.dropped, uint64(n))
+		return err
+	}
+	// the failed entries go before those written meanwhile
+	rest := w.buf.Bytes()
+	w.buf = bytes.Buffer{}
+	w.buf.Write(body)
+	w.buf.Write(rest)
+	w.n += n
+	return err
+}
+
+// Dropped returns the number of entries dropped while posts failed
+func (w *httpWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+func (w *httpWriter) post(body []byte) error {
+	if w.encode != nil {
+		var err error
+		if body, err = w.encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", w.contentType)
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return New(CodeLogShippingFailed, "log shipper got status "+strconv.Itoa(resp.StatusCode)+" from "+w.url)
+	}
+	return nil
+}
+
+func (w *httpWriter) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		w.wg.Wait()
+	})
+	return w.Sync()
+}
@@ -0,0 +1,388 @@
+package loggermanager
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Rotation periods of file sinks
+const (
+	RotateDaily  = "daily"
+	RotateHourly = "hourly"
+)
+
+const (
+	megabyte      = 1024 * 1024
+	sweepInterval = time.Hour
+)
+
+// rotatingWriter writes to a file which is renamed after a pattern when the period ends or it grows too big.
+// Rotated files are compressed and swept in the background.
+type rotatingWriter struct {
+	filename string
+	pattern  string // with %Y %m %d %H %M %S, next to filename unless absolute
+	period   string
+	maxSize  int64
+	compress bool
+
+	maxBackups int
+	maxAge     time.Duration
+	maxTotal   int64
+
+	now func() time.Time
+
+	mu        sync.Mutex
+	file      *os.File
+	size      int64
+	start     time.Time // start of the current period, names the next backup
+	periodEnd time.Time // zero without a period
+
+	sweepMu   sync.Mutex
+	kick      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func newRotatingWriter(cfg SinkConfig, now func() time.Time) *rotatingWriter {
+	w := &rotatingWriter{
+		filename:   cfg.Filename,
+		pattern:    cfg.Pattern,
+		period:     cfg.Rotate,
+		maxSize:    int64(cfg.MaxSize) * megabyte,
+		compress:   cfg.Compress,
+		maxBackups: cfg.MaxBackups,
+		maxAge:     time.Duration(cfg.MaxAge) * 24 * time.Hour,
+		maxTotal:   int64(cfg.MaxTotalSize) * megabyte,
+		now:        now,
+		kick:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	if w.pattern == "" {
+		w.pattern = defaultPattern(cfg.Filename, cfg.Rotate)
+	}
+	if !filepath.IsAbs(w.pattern) {
+		w.pattern = filepath.Join(filepath.Dir(cfg.Filename), w.pattern)
+	}
+	w.wg.Add(1)
+	go w.run()
+	return w
+}
+
+// defaultPattern puts the period start between the base name and the extension, e.g. app-2006-01-02.log
+func defaultPattern(filename, period string) string {
+	layout := "%Y-%m-%dT%H-%M-%S"
+	switch period {
+	case RotateDaily:
+		layout = "%Y-%m-%d"
+	case RotateHourly:
+		layout = "%Y-%m-%dT%H"
+	}
+	base := filepath.Base(filename)
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "-" + layout + ext
+}
+
+// expandPattern replaces %Y %m %d %H %M %S and %% with the values of t
+func expandPattern(pattern string, t time.Time) string {
+	return strings.NewReplacer(
+		"%Y", t.Format("2006"),
+		"%m", t.Format("01"),
+		"%d", t.Format("02"),
+		"%H", t.Format("15"),
+		"%M", t.Format("04"),
+		"%S", t.Format("05"),
+		"%%", "%",
+	).Replace(pattern)
+}
+
+// patternGlob matches every file made from pattern, including numbered and compressed ones.
+// It matches other files too, e.g. app-errors-2024-01-01.log for app-%Y-%m-%d.log, see patternRegexp.
+func patternGlob(pattern string) string {
+	glob := pattern
+	for _, tok := range []string{"%Y", "%m", "%d", "%H", "%M", "%S"} {
+		glob = strings.ReplaceAll(glob, tok, "*")
+	}
+	return strings.ReplaceAll(glob, "%%", "%") + "*"
+}
+
+// patternDigits matches the value of each pattern token
+var patternDigits = map[string]string{"%Y": `\d{4}`, "%m": `\d{2}`, "%d": `\d{2}`, "%H": `\d{2}`, "%M": `\d{2}`, "%S": `\d{2}`}
+
+// patternRegexp matches exactly the files made from pattern, including numbered and compressed ones
+func patternRegexp(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '%' && i+1 < len(pattern) {
+			tok := pattern[i : i+2]
+			if d, ok := patternDigits[tok]; ok {
+				sb.WriteString(d)
+				i++
+				continue
+			}
+			if tok == "%%" {
+				sb.WriteString("%")
+				i++
+				continue
+			}
+		}
+		sb.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+	}
+	sb.WriteString(`(\.\d+)?(\.gz)?$`)
+	return regexp.MustCompile(sb.String())
+}
+
+func (w *rotatingWriter) periodStart(t time.Time) time.Time {
+	switch w.period {
+	case RotateDaily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case RotateHourly:
+		// not Truncate, which works on absolute time and misses the hour in zones with a half hour offset
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	}
+	return t
+}
+
+func (w *rotatingWriter) nextPeriod(start time.Time) time.Time {
+	switch w.period {
+	case RotateDaily:
+		return start.AddDate(0, 0, 1)
+	case RotateHourly:
+		return start.Add(time.Hour)
+	}
+	return time.Time{}
+}
+
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	now := w.now()
+	if (!w.periodEnd.IsZero() && !now.Before(w.periodEnd)) || (w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize) {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *rotatingWriter) WriteLevel(_ zapcore.Level, p []byte) error {
+	_, err := w.Write(p)
+	return err
+}
+
+// open continues an existing file, whose period is taken from its modification time
+func (w *rotatingWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.filename), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	started := w.now()
+	if w.size > 0 {
+		started = info.ModTime()
+	}
+	w.start = w.periodStart(started)
+	w.periodEnd = w.nextPeriod(w.start)
+	return nil
+}
+
+func (w *rotatingWriter) rotate(now time.Time) error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+	name := w.start
+	if w.period == "" {
+		name = now
+	}
+	if err := os.Rename(w.filename, uniqueName(expandPattern(w.pattern, name))); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.start = w.periodStart(now)
+	w.periodEnd = w.nextPeriod(w.start)
+	select {
+	case w.kick <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// uniqueName appends .1, .2, ... when a backup of the same name exists already
+func uniqueName(name string) string {
+	candidate := name
+	for i := 1; ; i++ {
+		if !exists(candidate) && !exists(candidate+".gz") {
+			return candidate
+		}
+		candidate = name + "." + strconv.Itoa(i)
+	}
+}
+
+func exists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
+
+func (w *rotatingWriter) run() {
+	defer w.wg.Done()
+	t := time.NewTicker(sweepInterval)
+	defer t.Stop()
+	w.sweep()
+	for {
+		select {
+		case <-w.kick:
+		case <-t.C:
+		case <-w.done:
+			return
+		}
+		w.sweep()
+	}
+}
+
+// sweep compresses rotated files and removes those beyond the retention limits, oldest first
+func (w *rotatingWriter) sweep() {
+	w.sweepMu.Lock()
+	defer w.sweepMu.Unlock()
+
+	backups := w.backups()
+	if w.compress {
+		for i, b := range backups {
+			if strings.HasSuffix(b.name, ".gz") {
+				continue
+			}
+			if err := gzipFile(b.name); err == nil {
+				backups[i].name += ".gz"
+				if info, err := os.Stat(backups[i].name); err == nil {
+					backups[i].size = info.Size()
+				}
+			}
+		}
+	}
+
+	var total int64
+	if info, err := os.Stat(w.filename); err == nil {
+		total = info.Size()
+	}
+	cutoff := w.now().Add(-w.maxAge)
+	for i, b := range backups { // newest first
+		total += b.size
+		if (w.maxBackups > 0 && i >= w.maxBackups) ||
+			(w.maxAge > 0 && b.modTime.Before(cutoff)) ||
+			(w.maxTotal > 0 && total > w.maxTotal) {
+			os.Remove(b.name)
+		}
+	}
+}
+
+type backupFile struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+// backups lists the rotated files, newest first
+func (w *rotatingWriter) backups() []backupFile {
+	names, _ := filepath.Glob(patternGlob(w.pattern))
+	re := patternRegexp(filepath.Clean(w.pattern))
+	active, _ := filepath.Abs(w.filename)
+	var out []backupFile
+	for _, name := range names {
+		if abs, _ := filepath.Abs(name); abs == active || !re.MatchString(filepath.Clean(name)) {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		out = append(out, backupFile{name: name, size: info.Size(), modTime: info.ModTime()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].modTime.After(out[j].modTime) })
+	return out
+}
+
+// gzipFile replaces name by name.gz keeping its modification time
+func gzipFile(name string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	info, err := in.Stat()
+	if err != nil {
+		in.Close()
+		return err
+	}
+	out, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
+	if err != nil {
+		in.Close()
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err = io.Copy(zw, in); err == nil {
+		err = zw.Close()
+	}
+	// closed before the removal below, which fails on open files on windows
+	in.Close()
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+	os.Chtimes(name+".gz", info.ModTime(), info.ModTime())
+	return os.Remove(name)
+}
+
+func (w *rotatingWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+func (w *rotatingWriter) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+	})
+	w.wg.Wait()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
@@ -0,0 +1,156 @@
+package loggermanager
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) add(d time.Duration) {
+	c.mu.Lock()
+	c.t = c.t.Add(d)
+	c.mu.Unlock()
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotatingWriterDaily(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2024, 3, 1, 23, 0, 0, 0, time.Local)}
+	w := newRotatingWriter(SinkConfig{Filename: filepath.Join(dir, "app.log"), Rotate: RotateDaily, Compress: true}, clock.now)
+	defer w.Close()
+
+	w.Write([]byte("day one\n"))
+	clock.add(2 * time.Hour)
+	w.Write([]byte("day two\n"))
+	w.sweep()
+
+	got := dirNames(t, dir)
+	if len(got) != 2 || got[0] != "app-2024-03-01.log.gz" || got[1] != "app.log" {
+		t.Fatalf("files = %v", got)
+	}
+	f, err := os.Open(filepath.Join(dir, got[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(zr); string(b) != "day one\n" {
+		t.Errorf("backup = %q", b)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "app.log")); string(b) != "day two\n" {
+		t.Errorf("active = %q", b)
+	}
+}
+
+func TestRotatingWriterPatternAndSize(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2024, 3, 1, 10, 15, 0, 0, time.Local)}
+	w := newRotatingWriter(SinkConfig{Filename: filepath.Join(dir, "app.log"), Rotate: RotateHourly, Pattern: "archive-%Y%m%d%H.log", MaxSize: 1}, clock.now)
+	defer w.Close()
+
+	line := make([]byte, 700*1024)
+	w.Write(line)
+	w.Write(line) // exceeds 1MB within the hour
+	clock.add(time.Hour)
+	w.Write(line)
+
+	want := []string{"app.log", "archive-2024030110.log", "archive-2024030110.log.1"}
+	if got := dirNames(t, dir); len(got) != 3 || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+}
+
+func TestRotatingWriterRetention(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Now()}
+	w := newRotatingWriter(SinkConfig{Filename: filepath.Join(dir, "app.log"), Rotate: RotateDaily, MaxTotalSize: 2, MaxAge: 30}, clock.now)
+	defer w.Close()
+
+	chunk := make([]byte, 600*1024)
+	for day, age := range map[string]int{"2024-01-01": 40, "2024-01-02": 29, "2024-01-03": 18, "2024-01-04": 7} {
+		name := filepath.Join(dir, "app-"+day+".log")
+		os.WriteFile(name, chunk, 0644)
+		mod := clock.now().AddDate(0, 0, -age)
+		os.Chtimes(name, mod, mod)
+	}
+	os.WriteFile(filepath.Join(dir, "other.log"), chunk, 0644)
+	w.Write(chunk)
+	w.sweep()
+
+	// 01-01 is older than 30 days, 01-02 would take the active file and backups past 2MB
+	want := []string{"app-2024-01-03.log", "app-2024-01-04.log", "app.log", "other.log"}
+	got := dirNames(t, dir)
+	if len(got) != len(want) {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("files = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRotatingWriterIgnoresSiblings(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Now()}
+	w := newRotatingWriter(SinkConfig{Filename: filepath.Join(dir, "app.log"), Rotate: RotateDaily, MaxBackups: 2, Compress: true}, clock.now)
+	defer w.Close()
+
+	for _, name := range []string{"app-2024-01-01.log", "app-2024-01-02.log.1", "app-errors-2024-01-01.log", "app-errors.log"} {
+		os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644)
+	}
+	w.sweep()
+
+	// the backups of app-errors.log are neither compressed nor counted
+	want := []string{"app-2024-01-01.log.gz", "app-2024-01-02.log.1.gz", "app-errors-2024-01-01.log", "app-errors.log"}
+	got := dirNames(t, dir)
+	if len(got) != len(want) {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("files = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRotatingWriterHourlyHalfHourZone(t *testing.T) {
+	w := &rotatingWriter{period: RotateHourly}
+	zone := time.FixedZone("IST", 5*3600+1800)
+	got := w.periodStart(time.Date(2024, 3, 1, 10, 15, 0, 0, zone))
+	if want := time.Date(2024, 3, 1, 10, 0, 0, 0, zone); !got.Equal(want) {
+		t.Errorf("periodStart = %v, want %v", got, want)
+	}
+}
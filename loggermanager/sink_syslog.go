@@ -0,0 +1,48 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package loggermanager
+
+import (
+	"log/syslog"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// syslogWriter maps entry levels to syslog severities
+type syslogWriter struct {
+	w *syslog.Writer
+}
+
+func newSyslogWriter(cfg SinkConfig) (sinkWriter, error) {
+	w, err := syslog.Dial(cfg.Network, cfg.Address, syslog.LOG_INFO|syslog.LOG_USER, cfg.Tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogWriter{w: w}, nil
+}
+
+func (s *syslogWriter) WriteLevel(lvl zapcore.Level, p []byte) error {
+	msg := strings.TrimSuffix(string(p), "\n")
+	switch lvl {
+	case zapcore.DebugLevel:
+		return s.w.Debug(msg)
+	case zapcore.InfoLevel:
+		return s.w.Info(msg)
+	case zapcore.WarnLevel:
+		return s.w.Warning(msg)
+	case zapcore.ErrorLevel:
+		return s.w.Err(msg)
+	default:
+		return s.w.Crit(msg)
+	}
+}
+
+func (s *syslogWriter) Sync() error {
+	return nil
+}
+
+func (s *syslogWriter) Close() error {
+	return s.w.Close()
+}
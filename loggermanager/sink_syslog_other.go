@@ -0,0 +1,8 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package loggermanager
+
+func newSyslogWriter(cfg SinkConfig) (sinkWriter, error) {
+	return nil, New(CodeInvalidConfig, "syslog sink is not supported on this platform")
+}
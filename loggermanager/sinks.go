@@ -0,0 +1,314 @@
+package loggermanager
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	lumberjack "gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Sink types
+const (
+	SinkFile   = "file"
+	SinkStdout = "stdout"
+	SinkStderr = "stderr"
+	SinkSyslog = "syslog"
+	SinkHTTP   = "http"
+	SinkOTLP   = "otlp" // OTLP/HTTP JSON, e.g. http://localhost:4318/v1/logs
+)
+
+// Encodings
+const (
+	EncodingJSON    = "json"
+	EncodingConsole = "console"
+	EncodingOTel    = "otel" // OpenTelemetry log data model
+)
+
+// Overflow policies of async sinks
+const (
+	OverflowDrop  = "drop"
+	OverflowBlock = "block"
+)
+
+// SinkConfig describes one log destination
+type SinkConfig struct {
+	Type     string `json:"type" yaml:"type"`
+	Level    string `json:"level" yaml:"level"`       // minimum level of this sink, default debug
+	Encoding string `json:"encoding" yaml:"encoding"` // json, console or otel, default json, always otel for otlp
+
+	// file, rotated by size unless Rotate is daily or hourly
+	Filename   string `json:"filename" yaml:"filename"`
+	MaxSize    int    `json:"maxSize" yaml:"maxSize"` // megabytes
+	MaxBackups int    `json:"maxBackups" yaml:"maxBackups"`
+	MaxAge     int    `json:"maxAge" yaml:"maxAge"` // days
+	Compress   bool   `json:"compress" yaml:"compress"`
+	// Rotate, Pattern and MaxTotalSize switch to the time based rotator.
+	// Pattern names rotated files with %Y %m %d %H %M %S, relative to the directory of Filename,
+	// default "<name>-%Y-%m-%d<ext>" for daily rotation.
+	// MaxTotalSize caps the megabytes used by the file and its backups, oldest backups are removed first.
+	Rotate       string `json:"rotate" yaml:"rotate"`
+	Pattern      string `json:"pattern" yaml:"pattern"`
+	MaxTotalSize int    `json:"maxTotalSize" yaml:"maxTotalSize"`
+
+	// syslog, an empty Network dials the local syslog socket
+	Network string `json:"network" yaml:"network"`
+	Address string `json:"address" yaml:"address"`
+	Tag     string `json:"tag" yaml:"tag"`
+
+	// http, entries are POSTed as JSON lines. otlp uses the same settings.
+	URL           string            `json:"url" yaml:"url"`
+	Headers       map[string]string `json:"headers" yaml:"headers"`
+	BatchSize     int               `json:"batchSize" yaml:"batchSize"`         // default 100
+	FlushInterval time.Duration     `json:"flushInterval" yaml:"flushInterval"` // default 1s
+	Timeout       time.Duration     `json:"timeout" yaml:"timeout"`             // default 5s
+
+	// Async writes through a bounded buffer of BufferSize entries (default 1024).
+	// Overflow decides whether a full buffer drops entries or blocks the caller.
+	Async      bool   `json:"async" yaml:"async"`
+	BufferSize int    `json:"bufferSize" yaml:"bufferSize"`
+	Overflow   string `json:"overflow" yaml:"overflow"`
+
+	// legacyKeys keeps the short keys of the JSON files written by Init in older releases: L, T, M, C
+	legacyKeys bool
+}
+
+// sinkWriter is the output of a sink. The level lets syslog pick a severity.
+type sinkWriter interface {
+	WriteLevel(lvl zapcore.Level, p []byte) error
+	Sync() error
+	Close() error
+}
+
+// plainWriter adapts a WriteSyncer which does not care about levels
+type plainWriter struct {
+	zapcore.WriteSyncer
+	close func() error
+}
+
+func (w plainWriter) WriteLevel(_ zapcore.Level, p []byte) error {
+	_, err := w.Write(p)
+	return err
+}
+
+func (w plainWriter) Close() error {
+	if w.close == nil {
+		return w.Sync()
+	}
+	return w.close()
+}
+
+// sinkCore encodes entries for one sink
+type sinkCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	out sinkWriter
+}
+
+func (c *sinkCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	return &sinkCore{LevelEnabler: c.LevelEnabler, enc: enc, out: c.out}
+}
+
+func (c *sinkCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *sinkCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	err = c.out.WriteLevel(ent.Level, buf.Bytes())
+	buf.Free()
+	if err != nil {
+		return err
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		// entries above error may terminate the process
+		c.out.Sync()
+	}
+	return nil
+}
+
+func (c *sinkCore) Sync() error {
+	return c.out.Sync()
+}
+
+var (
+	sinksMu     sync.Mutex
+	activeSinks []sinkWriter
+)
+
+// InitSinks replaces the package logger with one writing to every sink.
+// Sinks of a previous call are flushed and closed.
+func InitSinks(sinks []SinkConfig) error {
+	cores, writers, err := buildSinks(sinks)
+	if err != nil {
+		return err
+	}
+	SetLogger(NewZapLogger(zap.New(wrapCore(cores...), zap.AddCaller())))
+	replaceSinks(writers)
+	return nil
+}
+
+func buildSinks(sinks []SinkConfig) ([]zapcore.Core, []sinkWriter, error) {
+	var (
+		cores   []zapcore.Core
+		writers []sinkWriter
+	)
+	fail := func(err error) ([]zapcore.Core, []sinkWriter, error) {
+		for _, w := range writers {
+			w.Close()
+		}
+		return nil, nil, err
+	}
+	for i := range sinks {
+		c, w, err := buildSink(sinks[i])
+		if err != nil {
+			return fail(err)
+		}
+		cores = append(cores, c)
+		writers = append(writers, w)
+	}
+	return cores, writers, nil
+}
+
+func buildSink(cfg SinkConfig) (zapcore.Core, sinkWriter, error) {
+	lvl := zapcore.DebugLevel
+	if cfg.Level != "" {
+		if err := lvl.UnmarshalText([]byte(cfg.Level)); err != nil {
+			return nil, nil, New(CodeInvalidConfig, "invalid level for "+cfg.Type+" sink: "+cfg.Level)
+		}
+	}
+	if cfg.Type == SinkOTLP {
+		cfg.Encoding = EncodingOTel
+	}
+	enc, err := newEncoder(cfg.Encoding)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.legacyKeys && cfg.Encoding == "" {
+		enc = zapcore.NewJSONEncoder(zap.NewDevelopmentEncoderConfig())
+	}
+	out, err := openSink(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.Async {
+		out = newAsyncWriter(out, cfg.BufferSize, cfg.Overflow == OverflowBlock)
+	}
+	return &sinkCore{LevelEnabler: lvl, enc: enc, out: out}, out, nil
+}
+
+func openSink(cfg SinkConfig) (sinkWriter, error) {
+	switch cfg.Type {
+	case SinkStdout:
+		return plainWriter{WriteSyncer: zapcore.Lock(os.Stdout)}, nil
+	case SinkStderr:
+		return plainWriter{WriteSyncer: zapcore.Lock(os.Stderr)}, nil
+	case SinkFile:
+		if cfg.Filename == "" {
+			return nil, New(CodeInvalidConfig, "file sink requires a filename")
+		}
+		if cfg.Rotate != "" || cfg.Pattern != "" || cfg.MaxTotalSize > 0 {
+			return newRotatingWriter(cfg, time.Now), nil
+		}
+		os.MkdirAll(filepath.Dir(cfg.Filename), os.ModePerm)
+		lj := &lumberjack.Logger{
+			Filename:   cfg.Filename,
+			MaxSize:    cfg.MaxSize, // megabytes
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAge, // days
+			Compress:   cfg.Compress,
+		}
+		return plainWriter{WriteSyncer: zapcore.AddSync(lj), close: lj.Close}, nil
+	case SinkSyslog:
+		return newSyslogWriter(cfg)
+	case SinkHTTP:
+		if cfg.URL == "" {
+			return nil, New(CodeInvalidConfig, "http sink requires a url")
+		}
+		return newHTTPWriter(cfg), nil
+	case SinkOTLP:
+		if cfg.URL == "" {
+			return nil, New(CodeInvalidConfig, "otlp sink requires a url")
+		}
+		w := newHTTPWriter(cfg)
+		w.contentType = "application/json"
+		w.encode = otlpBody
+		return w, nil
+	}
+	return nil, New(CodeInvalidConfig, "unknown sink type: "+cfg.Type)
+}
+
+func newEncoder(encoding string) (zapcore.Encoder, error) {
+	switch encoding {
+	case "", EncodingJSON:
+		return zapcore.NewJSONEncoder(jsonEncoderConfig()), nil
+	case EncodingConsole:
+		return zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig()), nil
+	case EncodingOTel:
+		return newOTelEncoder(), nil
+	}
+	return nil, New(CodeInvalidConfig, "unknown encoding: "+encoding)
+}
+
+func jsonEncoderConfig() zapcore.EncoderConfig {
+	cfg := zap.NewProductionEncoderConfig()
+	cfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	return cfg
+}
+
+// replaceSinks closes the previous sinks once the new logger is in place
+func replaceSinks(writers []sinkWriter) {
+	sinksMu.Lock()
+	prev := activeSinks
+	activeSinks = writers
+	sinksMu.Unlock()
+	for _, w := range prev {
+		w.Close()
+	}
+}
+
+// Close flushes and closes all sinks created by InitSinks. Call it on shutdown.
+func Close() error {
+	sinksMu.Lock()
+	prev := activeSinks
+	activeSinks = nil
+	sinksMu.Unlock()
+	var first error
+	for _, w := range prev {
+		if err := w.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// DroppedEntries returns the number of entries async sinks dropped because their buffer was full,
+// and http sinks dropped because the collector kept failing
+func DroppedEntries() uint64 {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	var n uint64
+	for _, w := range activeSinks {
+		if a, ok := w.(*asyncWriter); ok {
+			n += a.Dropped()
+			w = a.out
+		}
+		if h, ok := w.(*httpWriter); ok {
+			n += h.Dropped()
+		}
+	}
+	return n
+}
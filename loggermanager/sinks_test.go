@@ -0,0 +1,205 @@
+package loggermanager
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type collector struct {
+	mu    sync.Mutex
+	lines []map[string]interface{}
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, _ := io.ReadAll(r.Body)
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for sc.Scan() {
+		var m map[string]interface{}
+		json.Unmarshal(sc.Bytes(), &m)
+		c.lines = append(c.lines, m)
+	}
+}
+
+func (c *collector) messages() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []string
+	for _, l := range c.lines {
+		out = append(out, l["msg"].(string))
+	}
+	return out
+}
+
+func TestInitSinksHTTP(t *testing.T) {
+	col := &collector{}
+	srv := httptest.NewServer(col)
+	defer srv.Close()
+
+	prev := GetLogger()
+	defer SetLogger(prev)
+	defer Close()
+
+	err := InitSinks([]SinkConfig{
+		{Type: SinkHTTP, URL: srv.URL, Level: "warn", FlushInterval: time.Hour, Async: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	LogInfo("skipped")
+	LogError("shipped", String("key", "k1"))
+	if err := Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := col.messages()
+	if len(msgs) != 1 || msgs[0] != "shipped" {
+		t.Errorf("collector got %v, want [shipped]", msgs)
+	}
+}
+
+func TestHTTPWriterRetry(t *testing.T) {
+	col := &collector{}
+	var calls int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			<-release
+			col.ServeHTTP(w, r)
+		default:
+			col.ServeHTTP(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	w := newHTTPWriter(SinkConfig{URL: srv.URL, BatchSize: 2, FlushInterval: time.Hour})
+	defer w.Close()
+	line := func(msg string) []byte { return []byte(`{"msg":"` + msg + `"}` + "\n") }
+
+	w.WriteLevel(zapcore.InfoLevel, line("a"))
+	if err := w.Sync(); err == nil {
+		t.Fatal("expected the failed post to be reported")
+	}
+	done := make(chan error)
+	go func() { done <- w.Sync() }() // blocks in the second post
+	time.Sleep(20 * time.Millisecond)
+	written := make(chan struct{})
+	go func() {
+		w.WriteLevel(zapcore.InfoLevel, line("b"))
+		close(written)
+	}()
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatal("write blocked behind the post")
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	w.Sync()
+
+	if msgs := col.messages(); len(msgs) != 2 || msgs[0] != "a" || msgs[1] != "b" {
+		t.Errorf("collector got %v, want [a b]", msgs)
+	}
+	if w.Dropped() != 0 {
+		t.Errorf("dropped %d entries", w.Dropped())
+	}
+}
+
+func TestInitSinksInvalid(t *testing.T) {
+	for _, cfg := range []SinkConfig{
+		{Type: "kafka"},
+		{Type: SinkFile},
+		{Type: SinkStdout, Level: "loud"},
+		{Type: SinkStdout, Encoding: "xml"},
+	} {
+		if err := InitSinks([]SinkConfig{cfg}); err == nil {
+			t.Errorf("InitSinks(%+v) expected error", cfg)
+		}
+	}
+}
+
+type blockingWriter struct {
+	release chan struct{}
+	n       int
+}
+
+func (b *blockingWriter) WriteLevel(zapcore.Level, []byte) error {
+	<-b.release
+	b.n++
+	return nil
+}
+func (b *blockingWriter) Sync() error  { return nil }
+func (b *blockingWriter) Close() error { return nil }
+
+func TestAsyncWriterAfterClose(t *testing.T) {
+	for _, block := range []bool{true, false} {
+		out := &blockingWriter{release: make(chan struct{})}
+		close(out.release)
+		a := newAsyncWriter(out, 2, block)
+		a.WriteLevel(zapcore.InfoLevel, []byte("1"))
+		a.Close()
+		a.WriteLevel(zapcore.InfoLevel, []byte("2"))
+		if out.n != 1 || a.Dropped() != 1 {
+			t.Errorf("block %v: written %d, dropped %d", block, out.n, a.Dropped())
+		}
+	}
+}
+
+func TestHTTPWriterDoesNotBlock(t *testing.T) {
+	hung := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hung
+	}))
+	defer srv.Close()
+
+	w := newHTTPWriter(SinkConfig{URL: srv.URL, BatchSize: 2, FlushInterval: time.Hour, Timeout: time.Minute})
+	defer w.Close()
+	defer close(hung)
+	start := time.Now()
+	for i := 0; i < 50; i++ {
+		w.WriteLevel(zapcore.InfoLevel, []byte(`{"msg":"x"}`+"\n"))
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("writes took %v with a hung collector", d)
+	}
+	// one batch is posting, the buffer keeps httpRetainBatches batches
+	if d := w.Dropped(); d == 0 || d > 50-httpRetainBatches*2 {
+		t.Errorf("dropped = %d", d)
+	}
+}
+
+func TestAsyncWriterDrop(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	a := newAsyncWriter(out, 2, false)
+
+	// one entry is held by the blocked goroutine, two fill the buffer
+	a.WriteLevel(zapcore.InfoLevel, []byte("1"))
+	time.Sleep(10 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		a.WriteLevel(zapcore.InfoLevel, []byte("x"))
+	}
+	if d := a.Dropped(); d != 3 {
+		t.Errorf("dropped = %d, want 3", d)
+	}
+	close(out.release)
+	a.Close()
+	if out.n != 3 {
+		t.Errorf("written = %d, want 3", out.n)
+	}
+}
@@ -0,0 +1,66 @@
+package loggermanager
+
+import (
+	"go.uber.org/zap"
+)
+
+// zapLogger is the default Logger backend
+type zapLogger struct {
+	base *zap.Logger
+	s    *zap.SugaredLogger
+}
+
+// NewZapLogger wraps a zap logger so it can be used as the package backend via SetLogger
+func NewZapLogger(l *zap.Logger) Logger {
+	return newZapLogger(l.WithOptions(zap.AddCallerSkip(1)))
+}
+
+// newZapLogger expects l to already skip the zapLogger method frame
+func newZapLogger(l *zap.Logger) *zapLogger {
+	return &zapLogger{base: l, s: l.Sugar()}
+}
+
+// Debug -
+func (zl *zapLogger) Debug(msg string, keysAndValues ...interface{}) {
+	zl.s.Debugw(msg, keysAndValues...)
+}
+
+// Info -
+func (zl *zapLogger) Info(msg string, keysAndValues ...interface{}) {
+	zl.s.Infow(msg, keysAndValues...)
+}
+
+// Warn -
+func (zl *zapLogger) Warn(msg string, keysAndValues ...interface{}) {
+	zl.s.Warnw(msg, keysAndValues...)
+}
+
+// Error -
+func (zl *zapLogger) Error(msg string, keysAndValues ...interface{}) {
+	zl.s.Errorw(msg, keysAndValues...)
+}
+
+// Panic logs the message and then panics
+func (zl *zapLogger) Panic(msg string, keysAndValues ...interface{}) {
+	zl.s.Panicw(msg, keysAndValues...)
+}
+
+// Sync flushes buffered entries
+func (zl *zapLogger) Sync() error {
+	return zl.base.Sync()
+}
+
+func (zl *zapLogger) withCallerSkip(skip int) Logger {
+	return newZapLogger(zl.base.WithOptions(zap.AddCallerSkip(skip)))
+}
+
+// With -
+func (zl *zapLogger) With(keysAndValues ...interface{}) Logger {
+	s := zl.s.With(keysAndValues...)
+	return &zapLogger{base: s.Desugar(), s: s}
+}
+
+// Named -
+func (zl *zapLogger) Named(name string) Logger {
+	return newZapLogger(zl.base.Named(name))
+}
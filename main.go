@@ -1,4 +1,156 @@
 package main
 
+/*
+
+corelib logs queries the JSON log files written by loggermanager.
+
+	corelib logs [flags] file...
+
+	corelib logs -level warn -since 2h -logger mongodb data/logs/app.log
+	corelib logs -rotated -field request_id=req-42 -json data/logs/app.log
+	corelib logs -f -n 20 -caller cache_redis.go data/logs/app.log
+
+*/
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/crearosoft/corelib/loggermanager/logquery"
+	"go.uber.org/zap/zapcore"
+)
+
+type fieldFlags []logquery.FieldMatch
+
+func (f *fieldFlags) String() string {
+	return fmt.Sprint(*f)
+}
+
+func (f *fieldFlags) Set(s string) error {
+	*f = append(*f, logquery.ParseFieldMatch(s))
+	return nil
+}
+
 func main() {
+	if len(os.Args) < 2 || os.Args[1] != "logs" {
+		fmt.Fprintln(os.Stderr, "usage: corelib logs [flags] file...")
+		os.Exit(2)
+	}
+	if err := runLogs(os.Args[2:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "corelib logs:", err)
+		os.Exit(1)
+	}
+}
+
+func runLogs(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	var (
+		follow  = fs.Bool("f", false, "follow the last file, across rotations")
+		last    = fs.Int("n", 0, "print only the last n matching records before following, 0 prints all")
+		rotated = fs.Bool("rotated", false, "read the rotated backups of each file first, gzip included")
+		level   = fs.String("level", "", "minimum level")
+		since   = fs.String("since", "", "RFC 3339 time or duration before now, e.g. 2h")
+		until   = fs.String("until", "", "RFC 3339 time or duration before now")
+		logger  = fs.String("logger", "", "logger name, children included")
+		caller  = fs.String("caller", "", "substring of the caller, e.g. mongodb.go")
+		grep    = fs.String("grep", "", "substring of the message")
+		asJSON  = fs.Bool("json", false, "print JSON lines in the current encoding")
+		color   = fs.Bool("color", false, "color levels in pretty output")
+		poll    = fs.Duration("poll", 250*time.Millisecond, "poll interval when following")
+		fields  fieldFlags
+	)
+	fs.Var(&fields, "field", "key=value or key the records must have, repeatable")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no log file given")
+	}
+
+	filter := &logquery.Filter{Logger: *logger, Caller: *caller, Message: *grep, Fields: fields}
+	if *level != "" {
+		var lvl zapcore.Level
+		if err := lvl.UnmarshalText([]byte(*level)); err != nil {
+			return err
+		}
+		filter.Level = lvl
+	}
+	now := time.Now()
+	var err error
+	if *since != "" {
+		if filter.Since, err = logquery.ParseTime(*since, now); err != nil {
+			return err
+		}
+	}
+	if *until != "" {
+		if filter.Until, err = logquery.ParseTime(*until, now); err != nil {
+			return err
+		}
+	}
+
+	out := bufio.NewWriter(stdout)
+	defer out.Flush()
+	emit := func(r logquery.Record) {
+		if *asJSON {
+			logquery.WriteJSON(out, r)
+		} else {
+			logquery.WritePretty(out, r, *color)
+		}
+	}
+	var tail []logquery.Record
+	handle := func(line []byte) {
+		r, err := logquery.Parse(line)
+		if err != nil || !filter.Match(r) {
+			return
+		}
+		if *last <= 0 {
+			emit(r)
+			return
+		}
+		if len(tail) == *last {
+			tail = tail[1:]
+		}
+		tail = append(tail, r)
+	}
+
+	var offset int64
+	for _, name := range fs.Args() {
+		files := []string{name}
+		if *rotated {
+			if files, err = logquery.Files(name); err != nil {
+				return err
+			}
+		}
+		for _, file := range files {
+			if offset, err = logquery.ReadLines(file, handle); err != nil && !(*follow && os.IsNotExist(err)) {
+				return err
+			}
+		}
+	}
+	for _, r := range tail {
+		emit(r)
+	}
+	if !*follow {
+		return nil
+	}
+
+	*last = 0
+	out.Flush()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	active := fs.Arg(fs.NArg() - 1)
+	if strings.HasSuffix(active, ".gz") {
+		return fmt.Errorf("can not follow compressed file %s", active)
+	}
+	return logquery.Follow(ctx, active, offset, *poll, func(line []byte) {
+		handle(line)
+		out.Flush()
+	})
 }